@@ -0,0 +1,89 @@
+// Package apperror defines the domain errors returned by the repository and service layers
+// and writes them as a JSON error envelope with the matching HTTP status.
+package apperror
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type Code string
+
+const (
+	CodeInvalidRequest    Code = "invalid_request" // malformed request: bad JSON, ID or query parameter
+	CodeValidation        Code = "validation_error"
+	CodeNotFound          Code = "not_found"
+	CodeConflict          Code = "conflict" // the resource is not in a state that allows the action
+	CodeInsufficientStock Code = "insufficient_stock"
+	CodeUnauthorized      Code = "unauthorized"
+	CodeMethodNotAllowed  Code = "method_not_allowed"
+	CodeNotAcceptable     Code = "not_acceptable"
+	CodeInternal          Code = "internal_error"
+)
+
+// FieldError points a validation message at a request field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type Error struct {
+	Code    Code
+	Message string
+	Fields  []FieldError
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Status returns the HTTP status for the error code
+func (e *Error) Status() int {
+	switch e.Code {
+	case CodeInvalidRequest:
+		return http.StatusBadRequest
+	case CodeValidation:
+		return http.StatusUnprocessableEntity
+	case CodeNotFound:
+		return http.StatusNotFound
+	case CodeConflict, CodeInsufficientStock:
+		return http.StatusConflict
+	case CodeUnauthorized:
+		return http.StatusUnauthorized
+	case CodeMethodNotAllowed:
+		return http.StatusMethodNotAllowed
+	case CodeNotAcceptable:
+		return http.StatusNotAcceptable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func New(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func InvalidRequest(format string, args ...interface{}) *Error {
+	return New(CodeInvalidRequest, format, args...)
+}
+
+func Validation(format string, args ...interface{}) *Error {
+	return New(CodeValidation, format, args...)
+}
+
+func NotFound(format string, args ...interface{}) *Error {
+	return New(CodeNotFound, format, args...)
+}
+
+func Conflict(format string, args ...interface{}) *Error {
+	return New(CodeConflict, format, args...)
+}
+
+func InsufficientStock(format string, args ...interface{}) *Error {
+	return New(CodeInsufficientStock, format, args...)
+}
+
+// ValidationFields returns a validation error listing every invalid field
+func ValidationFields(fields []FieldError) *Error {
+	return &Error{Code: CodeValidation, Message: "Validation failed", Fields: fields}
+}
@@ -0,0 +1,112 @@
+package apperror
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// envelope is the body of every error response:
+// {"error": {"code": "not_found", "message": "No product found", "details": [...]}}
+type envelope struct {
+	Error body `json:"error"`
+}
+
+type body struct {
+	Code    Code         `json:"code"`
+	Message string       `json:"message"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// Write sends err as a JSON error envelope. Errors that are not domain errors are logged and
+// reported as an internal error so database details do not reach the client.
+func Write(w http.ResponseWriter, err error) {
+	appErr := From(err)
+	if appErr.Code == CodeInternal {
+		log.Println("Internal error:", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(appErr.Status())
+	json.NewEncoder(w).Encode(envelope{Error: body{
+		Code:    appErr.Code,
+		Message: appErr.Message,
+		Details: appErr.Fields,
+	}})
+}
+
+// WriteStatus sends a JSON error envelope for a request-level problem such as a malformed ID
+func WriteStatus(w http.ResponseWriter, status int, message string) {
+	code := CodeInternal
+	switch status {
+	case http.StatusBadRequest:
+		code = CodeInvalidRequest
+	case http.StatusUnprocessableEntity:
+		code = CodeValidation
+	case http.StatusNotFound:
+		code = CodeNotFound
+	case http.StatusConflict:
+		code = CodeConflict
+	case http.StatusUnauthorized:
+		code = CodeUnauthorized
+	case http.StatusMethodNotAllowed:
+		code = CodeMethodNotAllowed
+	case http.StatusNotAcceptable:
+		code = CodeNotAcceptable
+	}
+	Write(w, &Error{Code: code, Message: message})
+}
+
+// constraintFields names the request field behind the unique indexes a client can run into
+var constraintFields = map[string]string{
+	"idx_product_sku":                             "sku",
+	"idx_product_barcode":                         "barcode",
+	"product_serial_product_id_serial_number_key": "serial_numbers",
+}
+
+// From converts err to a domain error. Constraint violations raised by PostgreSQL become validation
+// or conflict errors with a fixed message; the PostgreSQL detail is logged, never sent to the client.
+// Anything else is an internal error.
+func From(err error) *Error {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		appErr := fromPQ(pqErr)
+		if appErr != nil {
+			log.Printf("Database error %s on %s (%s): %s %s", pqErr.Code.Name(), pqErr.Table, pqErr.Constraint, pqErr.Message, pqErr.Detail)
+			return appErr
+		}
+	}
+
+	return &Error{Code: CodeInternal, Message: "Internal server error"}
+}
+
+func fromPQ(pqErr *pq.Error) *Error {
+	switch pqErr.Code.Name() {
+	case "foreign_key_violation":
+		if strings.Contains(pqErr.Detail, "is still referenced") {
+			return Conflict("The record is still in use by other records")
+		}
+		return Validation("A referenced record does not exist")
+	case "unique_violation":
+		appErr := Conflict("A record with the same value already exists")
+		if field, ok := constraintFields[pqErr.Constraint]; ok {
+			appErr.Fields = []FieldError{{Field: field, Message: "is already in use"}}
+		}
+		return appErr
+	case "check_violation", "not_null_violation", "string_data_right_truncation", "numeric_value_out_of_range":
+		return Validation("A value is missing, too long or out of range")
+	case "invalid_text_representation", "invalid_datetime_format", "datetime_field_overflow":
+		return InvalidRequest("A value has an invalid format")
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+-- Modifier groups (e.g. "Sugar level", "Milk") and their options.
+CREATE TABLE IF NOT EXISTS modifier_group (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    min_select INT NOT NULL DEFAULT 0,
+    max_select INT NOT NULL DEFAULT 0 -- 0 means unlimited
+);
+
+CREATE TABLE IF NOT EXISTS modifier_option (
+    id SERIAL PRIMARY KEY,
+    group_id INT NOT NULL REFERENCES modifier_group(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    price_delta NUMERIC(12, 2) NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS product_modifier_group (
+    product_id INT NOT NULL REFERENCES product(id) ON DELETE CASCADE,
+    group_id INT NOT NULL REFERENCES modifier_group(id) ON DELETE CASCADE,
+    PRIMARY KEY (product_id, group_id)
+);
+
+ALTER TABLE transaction_detail ADD COLUMN IF NOT EXISTS note TEXT NOT NULL DEFAULT '';
+
+-- Chosen modifiers are snapshotted so receipts stay correct after the
+-- option is renamed, repriced or deleted.
+CREATE TABLE IF NOT EXISTS transaction_detail_modifier (
+    id SERIAL PRIMARY KEY,
+    transaction_detail_id INT NOT NULL REFERENCES transaction_detail(id) ON DELETE CASCADE,
+    modifier_option_id INT REFERENCES modifier_option(id) ON DELETE SET NULL,
+    group_name VARCHAR(100) NOT NULL,
+    option_name VARCHAR(100) NOT NULL,
+    price_delta NUMERIC(12, 2) NOT NULL DEFAULT 0
+);
@@ -0,0 +1,22 @@
+-- Append-only ledger of every stock change. product_id has no foreign key so
+-- the history survives when a product is deleted.
+CREATE TABLE IF NOT EXISTS stock_movement (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL,
+    movement_type VARCHAR(20) NOT NULL,
+    quantity_change INT NOT NULL,
+    quantity_before INT NOT NULL,
+    quantity_after INT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    actor VARCHAR(100) NOT NULL DEFAULT '',
+    reference VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_movement_product ON stock_movement (product_id, created_at);
+
+-- Opening balance for products that existed before the ledger.
+INSERT INTO stock_movement (product_id, movement_type, quantity_change, quantity_before, quantity_after, reason, actor)
+SELECT p.id, 'adjustment', p.stock, 0, p.stock, 'Opening balance', 'system'
+FROM product p
+WHERE NOT EXISTS (SELECT 1 FROM stock_movement sm WHERE sm.product_id = p.id);
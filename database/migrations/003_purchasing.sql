@@ -0,0 +1,43 @@
+CREATE TABLE IF NOT EXISTS supplier (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(150) NOT NULL,
+    phone VARCHAR(50) NOT NULL DEFAULT '',
+    address TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS purchase_order (
+    id SERIAL PRIMARY KEY,
+    supplier_id INT NOT NULL REFERENCES supplier(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'open', -- open, partial, received, cancelled
+    note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS purchase_order_line (
+    id SERIAL PRIMARY KEY,
+    purchase_order_id INT NOT NULL REFERENCES purchase_order(id) ON DELETE CASCADE,
+    product_id INT NOT NULL REFERENCES product(id),
+    quantity_ordered INT NOT NULL CHECK (quantity_ordered > 0),
+    quantity_received INT NOT NULL DEFAULT 0,
+    expected_cost NUMERIC(12, 2) NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS goods_receipt (
+    id SERIAL PRIMARY KEY,
+    purchase_order_id INT NOT NULL REFERENCES purchase_order(id),
+    note TEXT NOT NULL DEFAULT '',
+    actor VARCHAR(100) NOT NULL DEFAULT '',
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS goods_receipt_line (
+    id SERIAL PRIMARY KEY,
+    goods_receipt_id INT NOT NULL REFERENCES goods_receipt(id) ON DELETE CASCADE,
+    purchase_order_line_id INT NOT NULL REFERENCES purchase_order_line(id),
+    product_id INT NOT NULL REFERENCES product(id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    unit_cost NUMERIC(12, 2) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_order_supplier ON purchase_order (supplier_id);
+CREATE INDEX IF NOT EXISTS idx_goods_receipt_line_product ON goods_receipt_line (product_id);
@@ -0,0 +1,31 @@
+-- Stock opname (physical count) sessions.
+CREATE TABLE IF NOT EXISTS stock_count (
+    id SERIAL PRIMARY KEY,
+    status VARCHAR(20) NOT NULL DEFAULT 'open', -- open, approved, cancelled
+    note TEXT NOT NULL DEFAULT '',
+    created_by VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    approved_by VARCHAR(100) NOT NULL DEFAULT '',
+    approved_at TIMESTAMP
+);
+
+-- System quantity and unit value are snapshotted when the session starts.
+CREATE TABLE IF NOT EXISTS stock_count_line (
+    stock_count_id INT NOT NULL REFERENCES stock_count(id) ON DELETE CASCADE,
+    product_id INT NOT NULL REFERENCES product(id) ON DELETE CASCADE,
+    system_quantity INT NOT NULL,
+    counted_quantity INT,
+    unit_value NUMERIC(12, 2) NOT NULL DEFAULT 0,
+    PRIMARY KEY (stock_count_id, product_id)
+);
+
+-- Every batch submitted by a handheld device, kept for audit.
+CREATE TABLE IF NOT EXISTS stock_count_entry (
+    id SERIAL PRIMARY KEY,
+    stock_count_id INT NOT NULL REFERENCES stock_count(id) ON DELETE CASCADE,
+    product_id INT NOT NULL,
+    quantity INT NOT NULL,
+    device VARCHAR(100) NOT NULL DEFAULT '',
+    counted_by VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
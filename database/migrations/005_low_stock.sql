@@ -0,0 +1,4 @@
+ALTER TABLE product ADD COLUMN IF NOT EXISTS min_stock INT NOT NULL DEFAULT 0;
+ALTER TABLE product ADD COLUMN IF NOT EXISTS reorder_qty INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_transaction_created_at ON transaction (created_at);
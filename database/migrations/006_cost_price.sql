@@ -0,0 +1,15 @@
+-- Weighted average cost, updated on every goods receipt.
+ALTER TABLE product ADD COLUMN IF NOT EXISTS cost_price NUMERIC(12, 2) NOT NULL DEFAULT 0;
+
+-- Unit cost snapshotted at checkout for COGS reporting.
+ALTER TABLE transaction_detail ADD COLUMN IF NOT EXISTS unit_cost NUMERIC(12, 2) NOT NULL DEFAULT 0;
+
+-- Seed the cost of existing products from the goods already received.
+UPDATE product p
+SET cost_price = r.avg_cost
+FROM (
+    SELECT product_id, SUM(quantity * unit_cost) / SUM(quantity) AS avg_cost
+    FROM goods_receipt_line
+    GROUP BY product_id
+) r
+WHERE r.product_id = p.id AND p.cost_price = 0;
@@ -0,0 +1,22 @@
+ALTER TABLE product ADD COLUMN IF NOT EXISTS batch_tracked BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- For batch-tracked products the sum of batch quantities equals product.stock.
+CREATE TABLE IF NOT EXISTS product_batch (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES product(id) ON DELETE CASCADE,
+    lot_number VARCHAR(100) NOT NULL,
+    expiry_date DATE,
+    quantity INT NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    goods_receipt_line_id INT REFERENCES goods_receipt_line(id),
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_batch_fefo ON product_batch (product_id, expiry_date) WHERE quantity > 0;
+
+-- Which batches each sold line was taken from.
+CREATE TABLE IF NOT EXISTS transaction_detail_batch (
+    transaction_detail_id INT NOT NULL REFERENCES transaction_detail(id) ON DELETE CASCADE,
+    batch_id INT NOT NULL REFERENCES product_batch(id),
+    quantity INT NOT NULL,
+    PRIMARY KEY (transaction_detail_id, batch_id)
+);
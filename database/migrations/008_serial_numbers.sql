@@ -0,0 +1,18 @@
+ALTER TABLE product ADD COLUMN IF NOT EXISTS serial_tracked BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE transaction ADD COLUMN IF NOT EXISTS customer_name VARCHAR(150) NOT NULL DEFAULT '';
+ALTER TABLE transaction ADD COLUMN IF NOT EXISTS customer_phone VARCHAR(50) NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS product_serial (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES product(id) ON DELETE CASCADE,
+    serial_number VARCHAR(100) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'in_stock', -- in_stock, sold, removed
+    goods_receipt_line_id INT REFERENCES goods_receipt_line(id),
+    transaction_detail_id INT REFERENCES transaction_detail(id),
+    received_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    sold_at TIMESTAMP,
+    UNIQUE (product_id, serial_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_serial_number ON product_serial (serial_number);
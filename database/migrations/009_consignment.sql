@@ -0,0 +1,24 @@
+-- Consigned (titip jual) products belong to a supplier who is paid the agreed payout per unit sold.
+ALTER TABLE product ADD COLUMN IF NOT EXISTS consignor_id INT REFERENCES supplier(id);
+ALTER TABLE product ADD COLUMN IF NOT EXISTS consignment_payout NUMERIC(12, 2) NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS consignment_settlement (
+    id SERIAL PRIMARY KEY,
+    supplier_id INT NOT NULL REFERENCES supplier(id),
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    total_quantity INT NOT NULL DEFAULT 0,
+    total_payout NUMERIC(14, 2) NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'unpaid', -- unpaid, paid
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    paid_at TIMESTAMP,
+    paid_by VARCHAR(100) NOT NULL DEFAULT '',
+    payment_reference VARCHAR(100) NOT NULL DEFAULT ''
+);
+
+-- Consignor and payout are snapshotted per sold line; a line belongs to at most one settlement.
+ALTER TABLE transaction_detail ADD COLUMN IF NOT EXISTS consignor_id INT REFERENCES supplier(id);
+ALTER TABLE transaction_detail ADD COLUMN IF NOT EXISTS consignment_payout NUMERIC(12, 2) NOT NULL DEFAULT 0;
+ALTER TABLE transaction_detail ADD COLUMN IF NOT EXISTS consignment_settlement_id INT REFERENCES consignment_settlement(id);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_detail_consignor ON transaction_detail (consignor_id) WHERE consignor_id IS NOT NULL;
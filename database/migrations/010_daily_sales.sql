@@ -0,0 +1,23 @@
+-- Daily sales rollups for fast reports. sales_date is the day in the business timezone (BUSINESS_TZ).
+-- Checkout keeps them current; rebuild them from the transactions with
+--   go run main.go rebuild-daily-sales [start_date end_date]
+-- after applying this migration or changing BUSINESS_TZ.
+CREATE TABLE IF NOT EXISTS daily_sales (
+    sales_date DATE PRIMARY KEY,
+    transactions INT NOT NULL DEFAULT 0,
+    items INT NOT NULL DEFAULT 0,
+    revenue NUMERIC(14, 2) NOT NULL DEFAULT 0
+);
+
+-- product_id has no foreign key so the history survives when a product is deleted.
+CREATE TABLE IF NOT EXISTS daily_product_sales (
+    sales_date DATE NOT NULL,
+    product_id INT NOT NULL,
+    quantity INT NOT NULL DEFAULT 0,
+    revenue NUMERIC(14, 2) NOT NULL DEFAULT 0,
+    cogs NUMERIC(14, 2) NOT NULL DEFAULT 0,
+    transactions INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (sales_date, product_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_daily_product_sales_product ON daily_product_sales (product_id, sales_date);
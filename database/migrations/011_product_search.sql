@@ -0,0 +1,22 @@
+-- Product codes and the indexes behind GET /api/v1/products/search.
+-- pg_trgm ships with PostgreSQL as a contrib extension; creating it needs a role that may create extensions.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE product ADD COLUMN IF NOT EXISTS sku VARCHAR(50);
+ALTER TABLE product ADD COLUMN IF NOT EXISTS barcode VARCHAR(50);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_sku ON product (lower(sku));
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_barcode ON product (barcode);
+
+-- Full-text words of the product's own fields. The 'simple' configuration does not stem, which suits
+-- brand names and mixed Indonesian/English product names.
+ALTER TABLE product ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(sku, '') || ' ' || coalesce(barcode, ''))
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_product_search_vector ON product USING GIN (search_vector);
+
+-- Trigram indexes serve the typo-tolerant word similarity (<%) and substring matches
+CREATE INDEX IF NOT EXISTS idx_product_name_trgm ON product USING GIN (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_category_name_trgm ON category USING GIN (category gin_trgm_ops);
@@ -0,0 +1,12 @@
+-- Keys of the entry batches already applied to a count, so a batch resent by a handheld after a
+-- timeout is not counted twice.
+CREATE TABLE IF NOT EXISTS stock_count_batch (
+    stock_count_id INT NOT NULL REFERENCES stock_count(id) ON DELETE CASCADE,
+    batch_key VARCHAR(100) NOT NULL,
+    device VARCHAR(100) NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (stock_count_id, batch_key)
+);
+
+-- Entries that replaced the counted quantity instead of adding to it
+ALTER TABLE stock_count_entry ADD COLUMN IF NOT EXISTS replaced BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,17 @@
+-- Which serials each sold line carried. Rows are only ever added, so a refund or a resale of the same
+-- serial keeps the earlier sale on its receipt and in the warranty history.
+CREATE TABLE IF NOT EXISTS transaction_detail_serial (
+    transaction_detail_id INT NOT NULL REFERENCES transaction_detail(id) ON DELETE CASCADE,
+    serial_id INT NOT NULL REFERENCES product_serial(id) ON DELETE CASCADE,
+    sold_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (transaction_detail_id, serial_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_detail_serial_serial ON transaction_detail_serial (serial_id, sold_at);
+
+-- Sales recorded before this table existed only live on product_serial
+INSERT INTO transaction_detail_serial (transaction_detail_id, serial_id, sold_at)
+SELECT transaction_detail_id, id, COALESCE(sold_at, received_at)
+FROM product_serial
+WHERE transaction_detail_id IS NOT NULL
+ON CONFLICT DO NOTHING;
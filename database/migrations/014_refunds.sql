@@ -0,0 +1,5 @@
+-- Units of a sold line that a refund put back into the batch they were taken from
+ALTER TABLE transaction_detail_batch ADD COLUMN IF NOT EXISTS refunded_quantity INT NOT NULL DEFAULT 0;
+
+-- Refunds are summed per transaction reference to cap them at the quantity sold
+CREATE INDEX IF NOT EXISTS idx_stock_movement_reference ON stock_movement (product_id, reference) WHERE movement_type = 'refund';
@@ -2,11 +2,10 @@ package handler
 
 import (
 	"encoding/json"
+	"kasir-api/apperror"
 	"kasir-api/model"
 	"kasir-api/service"
 	"net/http"
-	"strconv"
-	"strings"
 )
 
 type CategoryHandler struct {
@@ -17,40 +16,34 @@ func NewCategoryHandler(service *service.CategoryService) *CategoryHandler {
 	return &CategoryHandler{service: service}
 }
 
-// HandleCategories - GET /api/categories
-func (h *CategoryHandler) HandleCategories(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		h.GetAllCategories(w, r)
-	case http.MethodPost:
-		h.Create(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// GetAllCategories - GET /api/v1/categories?name={name}&sort={id|name}&order={asc|desc}&limit={n}&offset={n}
+func (h *CategoryHandler) GetAllCategories(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r)
+	if err != nil {
+		apperror.Write(w, err)
+		return
 	}
-}
 
-func (h *CategoryHandler) GetAllCategories(w http.ResponseWriter, r *http.Request) {
-	category, err := h.service.GetAllCategories()
+	category, total, err := h.service.GetAllCategories(model.CategoryFilter{Name: r.URL.Query().Get("name"), ListOptions: opts})
 	if err != nil {
-		http.Error(w, "Failed to get categories", http.StatusInternalServerError)
+		apperror.Write(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(category)
+	writeList(w, category, total)
 }
 
 func (h *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var category model.Category
-	err := json.NewDecoder(r.Body).Decode(&category)
+	err := decodeJSON(r, &category)
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apperror.Write(w, err)
 		return
 	}
 
 	err = h.service.Create(&category)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apperror.Write(w, err)
 		return
 	}
 
@@ -58,32 +51,17 @@ func (h *CategoryHandler) Create(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(category)
 }
 
-// HandleCategoryByID - GET/PUT/DELETE /api/categories/{id}
-func (h *CategoryHandler) HandleCategoryByID(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		h.GetCategoryByID(w, r)
-	case http.MethodPut:
-		h.Update(w, r)
-	case http.MethodDelete:
-		h.Delete(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
-}
-
-// GetCategoryByID - GET /api/categories/{id}
+// GetCategoryByID - GET /api/v1/categories/{id}
 func (h *CategoryHandler) GetCategoryByID(w http.ResponseWriter, r *http.Request) {
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/categories/")
-	id, err := strconv.Atoi(idStr)
+	id, err := pathID(r, "id")
 	if err != nil {
-		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid category ID")
 		return
 	}
 
 	category, err := h.service.GetCategoryByID(id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apperror.Write(w, err)
 		return
 	}
 
@@ -91,26 +69,25 @@ func (h *CategoryHandler) GetCategoryByID(w http.ResponseWriter, r *http.Request
 	json.NewEncoder(w).Encode(category)
 }
 
-// Update - PUT /api/categories/{id}
+// Update - PUT /api/v1/categories/{id}
 func (h *CategoryHandler) Update(w http.ResponseWriter, r *http.Request) {
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/categories/")
-	id, err := strconv.Atoi(idStr)
+	id, err := pathID(r, "id")
 	if err != nil {
-		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid category ID")
 		return
 	}
 
 	var category model.Category
-	err = json.NewDecoder(r.Body).Decode(&category)
+	err = decodeJSON(r, &category)
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apperror.Write(w, err)
 		return
 	}
 
 	category.ID = id
 	err = h.service.Update(&category)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apperror.Write(w, err)
 		return
 	}
 
@@ -118,18 +95,17 @@ func (h *CategoryHandler) Update(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(category)
 }
 
-// Delete - DELETE /api/categories/{id}
+// Delete - DELETE /api/v1/categories/{id}
 func (h *CategoryHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/categories/")
-	id, err := strconv.Atoi(idStr)
+	id, err := pathID(r, "id")
 	if err != nil {
-		http.Error(w, "Invalid category ID", http.StatusBadRequest)
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid category ID")
 		return
 	}
 
 	err = h.service.Delete(id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperror.Write(w, err)
 		return
 	}
 
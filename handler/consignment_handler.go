@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"encoding/json"
+	"kasir-api/apperror"
+	"kasir-api/model"
+	"kasir-api/service"
+	"net/http"
+)
+
+type ConsignmentHandler struct {
+	service *service.ConsignmentService
+}
+
+func NewConsignmentHandler(service *service.ConsignmentService) *ConsignmentHandler {
+	return &ConsignmentHandler{service: service}
+}
+
+// GetConsignmentReport - GET /api/v1/consignment/report?start_date={start_date}&end_date={end_date}&supplier_id={id}
+func (h *ConsignmentHandler) GetConsignmentReport(w http.ResponseWriter, r *http.Request) {
+	supplierID, err := intQuery(r, "supplier_id", 0)
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid supplier ID")
+		return
+	}
+
+	report, err := h.service.GetConsignmentReport(r.URL.Query().Get("start_date"), r.URL.Query().Get("end_date"), supplierID)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// GetAllSettlements - GET /api/v1/consignment/settlements?supplier_id={id}&status={status}
+func (h *ConsignmentHandler) GetAllSettlements(w http.ResponseWriter, r *http.Request) {
+	supplierID, err := intQuery(r, "supplier_id", 0)
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid supplier ID")
+		return
+	}
+
+	settlements, err := h.service.GetAllSettlements(supplierID, r.URL.Query().Get("status"))
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settlements)
+}
+
+func (h *ConsignmentHandler) CreateSettlement(w http.ResponseWriter, r *http.Request) {
+	var input model.ConsignmentSettlementInput
+	err := decodeJSON(r, &input)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	settlement, err := h.service.CreateSettlement(&input)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(settlement)
+}
+
+// GetSettlementByID - GET /api/v1/consignment/settlements/{id}
+func (h *ConsignmentHandler) GetSettlementByID(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid settlement ID")
+		return
+	}
+
+	settlement, err := h.service.GetSettlementByID(id)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settlement)
+}
+
+// MarkPaid - POST /api/v1/consignment/settlements/{id}/pay
+func (h *ConsignmentHandler) MarkPaid(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid settlement ID")
+		return
+	}
+
+	var input model.ConsignmentPaymentInput
+	err = decodeJSON(r, &input)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	settlement, err := h.service.MarkPaid(id, &input)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(settlement)
+}
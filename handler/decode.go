@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"kasir-api/apperror"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// decodeJSON decodes the request body into dst. Unknown fields and values of the wrong type are
+// reported as field errors; anything else that is not a single JSON object is an invalid request.
+func decodeJSON(r *http.Request, dst interface{}) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil {
+		if dec.More() {
+			return apperror.InvalidRequest("Request body must contain a single JSON object")
+		}
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &typeErr):
+		return apperror.ValidationFields([]apperror.FieldError{
+			{Field: typeErr.Field, Message: fmt.Sprintf("must be of type %s", typeErr.Type)},
+		})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return apperror.ValidationFields([]apperror.FieldError{{Field: field, Message: "is not a known field"}})
+	case errors.Is(err, io.EOF):
+		return apperror.InvalidRequest("Request body is empty")
+	default:
+		return apperror.InvalidRequest("Invalid request body")
+	}
+}
+
+// pathID parses the {name} wildcard of the matched route as an integer ID
+func pathID(r *http.Request, name string) (int, error) {
+	return strconv.Atoi(r.PathValue(name))
+}
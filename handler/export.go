@@ -0,0 +1,291 @@
+package handler
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"kasir-api/apperror"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	formatJSON = "json"
+	formatCSV  = "csv"
+	formatXLSX = "xlsx"
+
+	xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// exportFormat picks the response format from the format query parameter, then the Accept header.
+// def is used when neither asks for CSV or XLSX.
+func exportFormat(r *http.Request, def string) (string, error) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "":
+	case formatJSON, formatCSV, formatXLSX:
+		return format, nil
+	default:
+		return "", apperror.InvalidRequest("Unknown format %s", format)
+	}
+
+	accept := r.Header.Get("Accept")
+	switch {
+	case strings.Contains(accept, xlsxContentType):
+		return formatXLSX, nil
+	case strings.Contains(accept, "text/csv"):
+		return formatCSV, nil
+	case strings.Contains(accept, "application/json"):
+		return formatJSON, nil
+	}
+	return def, nil
+}
+
+// tableWriter streams rows of a spreadsheet to the response
+type tableWriter interface {
+	WriteRow(values ...interface{}) error
+	Close() error
+}
+
+// newTableWriter sets the download headers for filename (without extension) and writes the header row
+func newTableWriter(w http.ResponseWriter, format string, filename string, header ...string) (tableWriter, error) {
+	var tw tableWriter
+	switch format {
+	case formatCSV:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		tw = &csvTableWriter{w: csv.NewWriter(w)}
+	case formatXLSX:
+		w.Header().Set("Content-Type", xlsxContentType)
+		xw, err := newXLSXTableWriter(w)
+		if err != nil {
+			return nil, err
+		}
+		tw = xw
+	default:
+		return nil, fmt.Errorf("Unknown format %s", format)
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+
+	values := make([]interface{}, len(header))
+	for i, h := range header {
+		values[i] = h
+	}
+	if err := tw.WriteRow(values...); err != nil {
+		return nil, err
+	}
+	return tw, nil
+}
+
+// writeExport answers a report request: as JSON data, or as a CSV/XLSX table of header and rows under filename
+func writeExport(w http.ResponseWriter, format string, data interface{}, filename string, header []string, rows [][]interface{}) {
+	if format == formatJSON {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(data)
+		return
+	}
+
+	tw, err := newTableWriter(w, format, filename, header...)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+	for _, row := range rows {
+		if err := tw.WriteRow(row...); err != nil {
+			log.Printf("Export %s: %v", filename, err)
+			return
+		}
+	}
+	if err := tw.Close(); err != nil {
+		log.Printf("Export %s: %v", filename, err)
+	}
+}
+
+// lazyTable starts the download on the first row, so an error raised before any row is produced
+// can still be answered with a status code
+type lazyTable struct {
+	w        http.ResponseWriter
+	format   string
+	filename string
+	header   []string
+	tw       tableWriter
+}
+
+func (l *lazyTable) WriteRow(values ...interface{}) error {
+	if l.tw == nil {
+		tw, err := newTableWriter(l.w, l.format, l.filename, l.header...)
+		if err != nil {
+			return err
+		}
+		l.tw = tw
+	}
+	return l.tw.WriteRow(values...)
+}
+
+// Finish closes the table after streaming ended with err. An empty export still gets its header row.
+func (l *lazyTable) Finish(err error) {
+	if err != nil {
+		if l.tw == nil {
+			apperror.Write(l.w, err)
+		} else {
+			log.Printf("Export %s: %v", l.filename, err)
+		}
+		return
+	}
+	if l.tw == nil {
+		tw, err := newTableWriter(l.w, l.format, l.filename, l.header...)
+		if err != nil {
+			apperror.Write(l.w, err)
+			return
+		}
+		l.tw = tw
+	}
+	if err := l.tw.Close(); err != nil {
+		log.Printf("Export %s: %v", l.filename, err)
+	}
+}
+
+// formatCell renders a value the way it should appear in a CSV cell
+func formatCell(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case *float64:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// escapeFormula keeps free text such as a customer name or line note from being run as a formula when
+// the CSV is opened in a spreadsheet. XLSX cells are written as inline strings, which are never formulas.
+func escapeFormula(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+type csvTableWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvTableWriter) WriteRow(values ...interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = formatCell(v)
+		if _, ok := v.(string); ok {
+			record[i] = escapeFormula(record[i])
+		}
+	}
+	if err := c.w.Write(record); err != nil {
+		return err
+	}
+	// Flush per row so large exports reach the client as they are read
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvTableWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// xlsxTableWriter writes a single-sheet workbook. The fixed parts are written up front so the
+// sheet can be streamed as the last zip entry without holding the rows in memory.
+type xlsxTableWriter struct {
+	zw    *zip.Writer
+	sheet io.Writer
+}
+
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+func newXLSXTableWriter(w io.Writer) (*xlsxTableWriter, error) {
+	zw := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		f, err := zw.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return nil, err
+	}
+	return &xlsxTableWriter{zw: zw, sheet: sheet}, nil
+}
+
+func (x *xlsxTableWriter) WriteRow(values ...interface{}) error {
+	var b strings.Builder
+	b.WriteString("<row>")
+	for _, v := range values {
+		switch v := v.(type) {
+		case int, float64:
+			fmt.Fprintf(&b, `<c t="n"><v>%s</v></c>`, formatCell(v))
+		case *float64:
+			if v == nil {
+				b.WriteString("<c/>")
+			} else {
+				fmt.Fprintf(&b, `<c t="n"><v>%s</v></c>`, formatCell(v))
+			}
+		default:
+			b.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			xml.EscapeText(&b, []byte(formatCell(v)))
+			b.WriteString("</t></is></c>")
+		}
+	}
+	b.WriteString("</row>")
+	_, err := io.WriteString(x.sheet, b.String())
+	return err
+}
+
+func (x *xlsxTableWriter) Close() error {
+	if _, err := io.WriteString(x.sheet, "</sheetData></worksheet>"); err != nil {
+		return err
+	}
+	return x.zw.Close()
+}
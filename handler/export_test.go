@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"io"
+	"kasir-api/apperror"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestExportFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		accept  string
+		def     string
+		want    string
+		wantErr bool
+	}{
+		{"default without query or Accept", "", "", formatJSON, formatJSON, false},
+		{"default of a CSV-only endpoint", "", "", formatCSV, formatCSV, false},
+		{"query parameter", "?format=xlsx", "", formatJSON, formatXLSX, false},
+		{"query parameter wins over Accept", "?format=csv", xlsxContentType, formatJSON, formatCSV, false},
+		{"JSON query parameter on a CSV-only endpoint", "?format=json", "", formatCSV, formatJSON, false},
+		{"Accept XLSX", "", xlsxContentType, formatJSON, formatXLSX, false},
+		{"Accept CSV among others", "", "text/csv, application/json;q=0.5", formatJSON, formatCSV, false},
+		{"Accept JSON on a CSV endpoint", "", "application/json", formatCSV, formatJSON, false},
+		{"Accept anything", "", "*/*", formatJSON, formatJSON, false},
+		{"unknown format", "?format=pdf", "", formatJSON, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/v1/reports/sales"+tt.query, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			got, err := exportFormat(r, tt.def)
+			if tt.wantErr {
+				var appErr *apperror.Error
+				if !errors.As(err, &appErr) || appErr.Code != apperror.CodeInvalidRequest {
+					t.Fatalf("err = %v, want an invalid request error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("exportFormat = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSVTableWriter(t *testing.T) {
+	price := 12500.5
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"plain text", "Indomie Goreng", "Indomie Goreng"},
+		{"formula", "=HYPERLINK(\"http://x\")", "'=HYPERLINK(\"http://x\")"},
+		{"plus", "+62812", "'+62812"},
+		{"minus", "-1+1", "'-1+1"},
+		{"at", "@SUM(A1)", "'@SUM(A1)"},
+		{"tab", "\tcmd", "'\tcmd"},
+		{"carriage return", "\rcmd", "'\rcmd"},
+		{"formula character later in the text", "a=b", "a=b"},
+		{"empty text", "", ""},
+		{"negative number is not text", -3, "-3"},
+		{"negative amount is not text", -1500.25, "-1500.25"},
+		{"amount", &price, "12500.5"},
+		{"missing amount", (*float64)(nil), ""},
+		{"time", time.Date(2026, 1, 10, 9, 15, 0, 0, time.UTC), "2026-01-10 09:15:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := &csvTableWriter{w: csv.NewWriter(&buf)}
+			// A second cell keeps a row with one empty cell from reading back as a blank line
+			if err := tw.WriteRow(tt.value, "end"); err != nil {
+				t.Fatal(err)
+			}
+			if err := tw.Close(); err != nil {
+				t.Fatal(err)
+			}
+			records, err := csv.NewReader(&buf).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(records) != 1 || len(records[0]) != 2 || records[0][0] != tt.want {
+				t.Errorf("cell = %q, want %q", records, tt.want)
+			}
+		})
+	}
+}
+
+// xlsxSheet is the part of sheet1.xml the tests read back
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func TestXLSXTableWriter(t *testing.T) {
+	total := 30000.0
+	rows := [][]interface{}{
+		{"Product", "Quantity", "Total", "Discount"},
+		{"Kopi <Susu> & Gula", 2, total, (*float64)(nil)},
+		{"=1+1", -1, 0.5, &total},
+	}
+	want := [][]struct{ typ, value string }{
+		{{"inlineStr", "Product"}, {"inlineStr", "Quantity"}, {"inlineStr", "Total"}, {"inlineStr", "Discount"}},
+		{{"inlineStr", "Kopi <Susu> & Gula"}, {"n", "2"}, {"n", "30000"}, {"", ""}},
+		{{"inlineStr", "=1+1"}, {"n", "-1"}, {"n", "0.5"}, {"n", "30000"}},
+	}
+
+	var buf bytes.Buffer
+	tw, err := newXLSXTableWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if err := tw.WriteRow(row...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("workbook is not a zip archive: %v", err)
+	}
+	names := make([]string, 0, len(zr.File))
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	for _, part := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if !slices.Contains(names, part) {
+			t.Errorf("workbook has no %s, parts: %v", part, names)
+		}
+	}
+	if last := names[len(names)-1]; last != "xl/worksheets/sheet1.xml" {
+		t.Errorf("last part = %s, want the streamed sheet", last)
+	}
+
+	f, err := zr.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sheet xlsxSheet
+	if err := xml.Unmarshal(content, &sheet); err != nil {
+		t.Fatalf("sheet is not valid XML: %v\n%s", err, content)
+	}
+
+	if len(sheet.Rows) != len(want) {
+		t.Fatalf("sheet has %d rows, want %d", len(sheet.Rows), len(want))
+	}
+	for i, row := range sheet.Rows {
+		if len(row.Cells) != len(want[i]) {
+			t.Fatalf("row %d has %d cells, want %d", i+1, len(row.Cells), len(want[i]))
+		}
+		for j, c := range row.Cells {
+			value := c.Value
+			if c.Type == "inlineStr" {
+				value = c.Inline
+			}
+			if c.Type != want[i][j].typ || value != want[i][j].value {
+				t.Errorf("row %d cell %d = %s %q, want %s %q", i+1, j+1, c.Type, value, want[i][j].typ, want[i][j].value)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"encoding/json"
+	"kasir-api/apperror"
+	"kasir-api/model"
+	"net/http"
+	"strconv"
+)
+
+// listOptions reads the sort, order, limit and offset query parameters of a list endpoint
+func listOptions(r *http.Request) (model.ListOptions, error) {
+	opts := model.ListOptions{
+		SortBy: r.URL.Query().Get("sort"),
+		Order:  r.URL.Query().Get("order"),
+	}
+	var err error
+	if opts.Limit, err = intQuery(r, "limit", 0); err != nil {
+		return opts, apperror.InvalidRequest("Invalid limit")
+	}
+	if opts.Offset, err = intQuery(r, "offset", 0); err != nil {
+		return opts, apperror.InvalidRequest("Invalid offset")
+	}
+	return opts, nil
+}
+
+// optionalFloatQuery reads a decimal query parameter, returning nil when it is absent
+func optionalFloatQuery(r *http.Request, key string) (*float64, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// boolQuery reads a true/false query parameter, falling back to def when it is absent
+func boolQuery(r *http.Request, key string, def bool) (bool, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.ParseBool(value)
+}
+
+// writeList sends one page of a list as a JSON array, with the number of matches across all
+// pages in the X-Total-Count header so clients that expect a plain array keep working
+func writeList(w http.ResponseWriter, items interface{}, total int) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
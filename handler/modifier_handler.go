@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"encoding/json"
+	"kasir-api/apperror"
+	"kasir-api/model"
+	"kasir-api/service"
+	"net/http"
+	"strconv"
+)
+
+type ModifierHandler struct {
+	service *service.ModifierService
+}
+
+func NewModifierHandler(service *service.ModifierService) *ModifierHandler {
+	return &ModifierHandler{service: service}
+}
+
+// GetAllModifierGroups - GET /api/v1/modifiers?product_id={id}
+func (h *ModifierHandler) GetAllModifierGroups(w http.ResponseWriter, r *http.Request) {
+	var groups []model.ModifierGroup
+	var err error
+
+	productIDStr := r.URL.Query().Get("product_id")
+	if productIDStr != "" {
+		productID, convErr := strconv.Atoi(productIDStr)
+		if convErr != nil {
+			apperror.WriteStatus(w, http.StatusBadRequest, "Invalid product ID")
+			return
+		}
+		groups, err = h.service.GetModifierGroupsByProductID(productID)
+	} else {
+		groups, err = h.service.GetAllModifierGroups()
+	}
+
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(groups)
+}
+
+func (h *ModifierHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var group model.ModifierGroup
+	err := decodeJSON(r, &group)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	err = h.service.Create(&group)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(group)
+}
+
+// GetModifierGroupByID - GET /api/v1/modifiers/{id}
+func (h *ModifierHandler) GetModifierGroupByID(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid modifier group ID")
+		return
+	}
+
+	group, err := h.service.GetModifierGroupByID(id)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
+}
+
+// Update - PUT /api/v1/modifiers/{id}
+func (h *ModifierHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid modifier group ID")
+		return
+	}
+
+	var group model.ModifierGroup
+	err = decodeJSON(r, &group)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	group.ID = id
+	err = h.service.Update(&group)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(group)
+}
+
+// Delete - DELETE /api/v1/modifiers/{id}
+func (h *ModifierHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid modifier group ID")
+		return
+	}
+
+	err = h.service.Delete(id)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Modifier group deleted successfully",
+	})
+}
@@ -2,57 +2,109 @@ package handler
 
 import (
 	"encoding/json"
+	"kasir-api/apperror"
 	"kasir-api/model"
 	"kasir-api/service"
 	"net/http"
-	"strconv"
-	"strings"
 )
 
 type ProductHandler struct {
-	service *service.ProductService
+	service      *service.ProductService
+	stockService *service.StockService
 }
 
-func NewProductHandler(service *service.ProductService) *ProductHandler {
-	return &ProductHandler{service: service}
+func NewProductHandler(service *service.ProductService, stockService *service.StockService) *ProductHandler {
+	return &ProductHandler{service: service, stockService: stockService}
 }
 
-// HandleProducts - GET /api/produk
-func (h *ProductHandler) HandleProducts(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		h.GetAllProducts(w, r)
-	case http.MethodPost:
-		h.Create(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// GetAllProducts - GET /api/v1/products?name={name}&category_id={id}&min_price={price}&max_price={price}&in_stock={bool}&low_stock={bool}&sort={id|name|price|stock}&order={asc|desc}&limit={n}&offset={n}
+func (h *ProductHandler) GetAllProducts(w http.ResponseWriter, r *http.Request) {
+	filter, err := productFilter(r)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	products, total, err := h.service.GetAllProducts(filter)
+	if err != nil {
+		apperror.Write(w, err)
+		return
 	}
+
+	writeList(w, products, total)
 }
 
-func (h *ProductHandler) GetAllProducts(w http.ResponseWriter, r *http.Request) {
-	name := r.URL.Query().Get("name")
-	// fmt.Println(name)
-	product, err := h.service.GetAllProducts(name)
+// productFilter reads the filters, sorting and paging of the product list
+func productFilter(r *http.Request) (model.ProductFilter, error) {
+	filter := model.ProductFilter{Name: r.URL.Query().Get("name")}
+	var err error
+	if filter.ListOptions, err = listOptions(r); err != nil {
+		return filter, err
+	}
+	if filter.CategoryID, err = intQuery(r, "category_id", 0); err != nil {
+		return filter, apperror.InvalidRequest("Invalid category_id")
+	}
+	if filter.MinPrice, err = optionalFloatQuery(r, "min_price"); err != nil {
+		return filter, apperror.InvalidRequest("Invalid min_price")
+	}
+	if filter.MaxPrice, err = optionalFloatQuery(r, "max_price"); err != nil {
+		return filter, apperror.InvalidRequest("Invalid max_price")
+	}
+	if filter.InStock, err = boolQuery(r, "in_stock", false); err != nil {
+		return filter, apperror.InvalidRequest("Invalid in_stock")
+	}
+	if filter.LowStock, err = boolQuery(r, "low_stock", false); err != nil {
+		return filter, apperror.InvalidRequest("Invalid low_stock")
+	}
+	return filter, nil
+}
+
+// SearchProducts - GET /api/v1/products/search?q={text}&limit={n}&boost_sales={bool}
+func (h *ProductHandler) SearchProducts(w http.ResponseWriter, r *http.Request) {
+	limit, err := intQuery(r, "limit", 0)
 	if err != nil {
-		http.Error(w, "Failed to get products, check if exists", http.StatusInternalServerError)
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+	boostSales, err := boolQuery(r, "boost_sales", false)
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid boost_sales")
+		return
+	}
+
+	results, err := h.service.SearchProducts(r.URL.Query().Get("q"), limit, boostSales)
+	if err != nil {
+		apperror.Write(w, err)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(product)
+	json.NewEncoder(w).Encode(results)
+}
+
+// GetLowStockProducts - GET /api/v1/products/low-stock
+func (h *ProductHandler) GetLowStockProducts(w http.ResponseWriter, r *http.Request) {
+	products, err := h.service.GetLowStockProducts()
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(products)
 }
 
 func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 	var input model.ProductInput
-	err := json.NewDecoder(r.Body).Decode(&input)
+	err := decodeJSON(r, &input)
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apperror.Write(w, err)
 		return
 	}
 
 	product, err := h.service.Create(&input)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apperror.Write(w, err)
 		return
 	}
 
@@ -61,32 +113,17 @@ func (h *ProductHandler) Create(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(product)
 }
 
-// HandleProductByID - GET/PUT/DELETE /api/produk/{id}
-func (h *ProductHandler) HandleProductByID(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		h.GetProductByID(w, r)
-	case http.MethodPut:
-		h.Update(w, r)
-	case http.MethodDelete:
-		h.Delete(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
-}
-
-// GetProductByID - GET /api/produk/{id}
+// GetProductByID - GET /api/v1/products/{id}
 func (h *ProductHandler) GetProductByID(w http.ResponseWriter, r *http.Request) {
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/produk/")
-	id, err := strconv.Atoi(idStr)
+	id, err := pathID(r, "id")
 	if err != nil {
-		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid product ID")
 		return
 	}
 
 	product, err := h.service.GetProductByID(id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		apperror.Write(w, err)
 		return
 	}
 
@@ -95,23 +132,22 @@ func (h *ProductHandler) GetProductByID(w http.ResponseWriter, r *http.Request)
 }
 
 func (h *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/produk/")
-	id, err := strconv.Atoi(idStr)
+	id, err := pathID(r, "id")
 	if err != nil {
-		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid product ID")
 		return
 	}
 
 	var input model.ProductInput
-	err = json.NewDecoder(r.Body).Decode(&input)
+	err = decodeJSON(r, &input)
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apperror.Write(w, err)
 		return
 	}
 
 	product, err := h.service.Update(id, &input)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apperror.Write(w, err)
 		return
 	}
 
@@ -119,18 +155,17 @@ func (h *ProductHandler) Update(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(product)
 }
 
-// Delete - DELETE /api/produk/{id}
+// Delete - DELETE /api/v1/products/{id}
 func (h *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	idStr := strings.TrimPrefix(r.URL.Path, "/api/produk/")
-	id, err := strconv.Atoi(idStr)
+	id, err := pathID(r, "id")
 	if err != nil {
-		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid product ID")
 		return
 	}
 
 	err = h.service.Delete(id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperror.Write(w, err)
 		return
 	}
 
@@ -139,3 +174,65 @@ func (h *ProductHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		"message": "Product deleted successfully",
 	})
 }
+
+// GetStockHistory - GET /api/v1/products/{id}/stock-history
+func (h *ProductHandler) GetStockHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	movements, err := h.stockService.GetStockHistory(id)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(movements)
+}
+
+// AdjustStock - POST /api/v1/products/{id}/stock-adjustments
+func (h *ProductHandler) AdjustStock(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	var input model.StockAdjustmentInput
+	err = decodeJSON(r, &input)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	movement, err := h.stockService.Adjust(id, &input)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(movement)
+}
+
+// GetBatches - GET /api/v1/products/{id}/batches
+func (h *ProductHandler) GetBatches(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+
+	batches, err := h.stockService.GetBatches(id)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(batches)
+}
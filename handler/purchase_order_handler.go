@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"encoding/json"
+	"kasir-api/apperror"
+	"kasir-api/model"
+	"kasir-api/service"
+	"net/http"
+)
+
+type PurchaseOrderHandler struct {
+	service *service.PurchaseOrderService
+}
+
+func NewPurchaseOrderHandler(service *service.PurchaseOrderService) *PurchaseOrderHandler {
+	return &PurchaseOrderHandler{service: service}
+}
+
+// GetAllPurchaseOrders - GET /api/v1/purchase-orders?status={status}
+func (h *PurchaseOrderHandler) GetAllPurchaseOrders(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	orders, err := h.service.GetAllPurchaseOrders(status)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(orders)
+}
+
+func (h *PurchaseOrderHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var input model.PurchaseOrderInput
+	err := decodeJSON(r, &input)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	order, err := h.service.Create(&input)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+// GetPurchaseOrderByID - GET /api/v1/purchase-orders/{id}
+func (h *PurchaseOrderHandler) GetPurchaseOrderByID(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid purchase order ID")
+		return
+	}
+
+	order, err := h.service.GetPurchaseOrderByID(id)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
+
+// Receive - POST /api/v1/purchase-orders/{id}/receipts
+func (h *PurchaseOrderHandler) Receive(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid purchase order ID")
+		return
+	}
+
+	var input model.GoodsReceiptInput
+	err = decodeJSON(r, &input)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	receipt, err := h.service.Receive(id, &input)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(receipt)
+}
+
+// Cancel - POST /api/v1/purchase-orders/{id}/cancel
+func (h *PurchaseOrderHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid purchase order ID")
+		return
+	}
+
+	order, err := h.service.Cancel(id)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(order)
+}
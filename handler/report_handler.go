@@ -0,0 +1,364 @@
+package handler
+
+import (
+	"fmt"
+	"kasir-api/apperror"
+	"kasir-api/model"
+	"kasir-api/service"
+	"net/http"
+	"strconv"
+)
+
+type ReportHandler struct {
+	service *service.ReportService
+}
+
+func NewReportHandler(service *service.ReportService) *ReportHandler {
+	return &ReportHandler{service: service}
+}
+
+// GetReorderSuggestions - GET /api/v1/reports/reorder?days={days}&cover_days={cover_days}&format={json|csv|xlsx}
+func (h *ReportHandler) GetReorderSuggestions(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r, formatJSON)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+	days, err := intQuery(r, "days", 30)
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid days")
+		return
+	}
+	coverDays, err := intQuery(r, "cover_days", 14)
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid cover_days")
+		return
+	}
+
+	suggestions, err := h.service.GetReorderSuggestions(days, coverDays)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	rows := make([][]interface{}, 0, len(suggestions))
+	for _, s := range suggestions {
+		rows = append(rows, []interface{}{s.ProductID, s.ProductName, s.Stock, s.MinStock, s.ReorderQty, s.QuantitySold, s.AvgDailySales, s.DaysOfCover, s.SuggestedQty})
+	}
+	writeExport(w, format, suggestions, "reorder-suggestions",
+		[]string{"product_id", "product_name", "stock", "min_stock", "reorder_qty", "quantity_sold", "avg_daily_sales", "days_of_cover", "suggested_qty"}, rows)
+}
+
+// GetExpiringBatches - GET /api/v1/reports/expiring?days={days}&format={json|csv|xlsx}
+func (h *ReportHandler) GetExpiringBatches(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r, formatJSON)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+	days, err := intQuery(r, "days", 30)
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid days")
+		return
+	}
+
+	batches, err := h.service.GetExpiringBatches(days)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	rows := make([][]interface{}, 0, len(batches))
+	for _, b := range batches {
+		expiry := ""
+		if b.ExpiryDate != nil {
+			expiry = b.ExpiryDate.Format("2006-01-02")
+		}
+		rows = append(rows, []interface{}{b.ID, b.ProductID, b.ProductName, b.LotNumber, expiry, b.DaysUntilExpiry, b.Expired, b.Quantity, b.StockValue})
+	}
+	writeExport(w, format, batches, "expiring-batches",
+		[]string{"batch_id", "product_id", "product_name", "lot_number", "expiry_date", "days_until_expiry", "expired", "quantity", "stock_value"}, rows)
+}
+
+// GetProductSales - GET /api/v1/reports/products?start_date={start_date}&end_date={end_date}&limit={n}&sort={field}&category_id={id}&tz={timezone}&format={json|csv|xlsx}
+func (h *ReportHandler) GetProductSales(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r, formatJSON)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+	limit, err := intQuery(r, "limit", 0)
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+	categoryID, err := intQuery(r, "category_id", 0)
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid category ID")
+		return
+	}
+
+	sales, err := h.service.GetProductSales(model.ProductSalesFilter{
+		StartDate:  r.URL.Query().Get("start_date"),
+		EndDate:    r.URL.Query().Get("end_date"),
+		SortBy:     r.URL.Query().Get("sort"),
+		CategoryID: categoryID,
+		Limit:      limit,
+		Timezone:   r.URL.Query().Get("tz"),
+	})
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	rows := make([][]interface{}, 0, len(sales))
+	for _, s := range sales {
+		rows = append(rows, []interface{}{s.Rank, s.ProductID, s.ProductName, s.CategoryID, s.CategoryName, s.Quantity, s.Revenue, s.TransactionCount})
+	}
+	writeExport(w, format, sales, "product-sales",
+		[]string{"rank", "product_id", "product_name", "category_id", "category_name", "quantity", "revenue", "transaction_count"}, rows)
+}
+
+// GetSalesTimeSeries - GET /api/v1/reports/timeseries?start_date={start_date}&end_date={end_date}&interval={hour|day|week|month}&tz={timezone}&format={json|csv|xlsx}
+func (h *ReportHandler) GetSalesTimeSeries(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r, formatJSON)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	series, err := h.service.GetSalesTimeSeries(
+		r.URL.Query().Get("start_date"),
+		r.URL.Query().Get("end_date"),
+		r.URL.Query().Get("interval"),
+		r.URL.Query().Get("tz"),
+	)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	rows := make([][]interface{}, 0, len(series.Buckets))
+	for _, b := range series.Buckets {
+		rows = append(rows, []interface{}{b.Start, b.Revenue, b.TransactionCount})
+	}
+	writeExport(w, format, series, "sales-"+series.Interval, []string{"start", "revenue", "transaction_count"}, rows)
+}
+
+// GetCategorySales - GET /api/v1/reports/categories?start_date={start_date}&end_date={end_date}&tz={timezone}&format={json|csv|xlsx}
+func (h *ReportHandler) GetCategorySales(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r, formatJSON)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	report, err := h.service.GetCategorySales(r.URL.Query().Get("start_date"), r.URL.Query().Get("end_date"), r.URL.Query().Get("tz"))
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	rows := make([][]interface{}, 0, len(report.Categories))
+	for _, c := range report.Categories {
+		rows = append(rows, []interface{}{c.CategoryID, c.CategoryName, c.Quantity, c.Revenue, c.SharePct, c.PreviousQuantity, c.PreviousRevenue, c.RevenueChangePct})
+	}
+	writeExport(w, format, report, "category-sales",
+		[]string{"category_id", "category_name", "quantity", "revenue", "share_pct", "previous_quantity", "previous_revenue", "revenue_change_pct"}, rows)
+}
+
+// GetSlowMovers - GET /api/v1/reports/slow-movers?days={days}&threshold={qty}&sort={field}&format={json|csv|xlsx}
+func (h *ReportHandler) GetSlowMovers(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r, formatJSON)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+	days, err := intQuery(r, "days", 30)
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid days")
+		return
+	}
+	threshold, err := intQuery(r, "threshold", 0)
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid threshold")
+		return
+	}
+
+	products, err := h.service.GetSlowMovers(days, threshold, r.URL.Query().Get("sort"))
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	rows := make([][]interface{}, 0, len(products))
+	for _, p := range products {
+		var lastSold, daysSince interface{} = "", ""
+		if p.LastSoldAt != nil {
+			lastSold, daysSince = *p.LastSoldAt, *p.DaysSinceLastSale
+		}
+		rows = append(rows, []interface{}{p.ProductID, p.ProductName, p.CategoryName, p.Stock, p.QuantitySold, lastSold, daysSince, p.StockValue})
+	}
+	writeExport(w, format, products, "slow-movers",
+		[]string{"product_id", "product_name", "category_name", "stock", "quantity_sold", "last_sold_at", "days_since_last_sale", "stock_value"}, rows)
+}
+
+// GetInventoryValuation - GET /api/v1/reports/inventory-valuation?as_of={date}&tz={timezone}&format={json|csv|xlsx}
+func (h *ReportHandler) GetInventoryValuation(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r, formatJSON)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	valuation, err := h.service.GetInventoryValuation(r.URL.Query().Get("as_of"), r.URL.Query().Get("tz"))
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	rows := make([][]interface{}, 0, len(valuation.Products)+1)
+	for _, p := range valuation.Products {
+		rows = append(rows, []interface{}{p.ProductID, p.ProductName, p.CategoryID, p.CategoryName, p.Quantity, p.UnitValue, p.ValueBasis, p.Value})
+	}
+	rows = append(rows, []interface{}{"", "TOTAL", "", "", valuation.TotalQuantity, "", "", valuation.TotalValue})
+	writeExport(w, format, valuation, "inventory-valuation",
+		[]string{"product_id", "product_name", "category_id", "category_name", "quantity", "unit_value", "value_basis", "value"}, rows)
+}
+
+// GetProductPairs - GET /api/v1/reports/basket?start_date={start_date}&end_date={end_date}&min_count={n}&limit={n}&tz={timezone}&format={json|csv|xlsx}
+func (h *ReportHandler) GetProductPairs(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r, formatJSON)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+	minCount, err := intQuery(r, "min_count", 2)
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid min_count")
+		return
+	}
+	limit, err := intQuery(r, "limit", 50)
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	pairs, err := h.service.GetProductPairs(
+		r.URL.Query().Get("start_date"),
+		r.URL.Query().Get("end_date"),
+		r.URL.Query().Get("tz"),
+		minCount,
+		limit,
+	)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	rows := make([][]interface{}, 0, len(pairs))
+	for _, p := range pairs {
+		rows = append(rows, []interface{}{p.ProductAID, p.ProductAName, p.ProductBID, p.ProductBName, p.Transactions, p.Support, p.ConfidenceAToB, p.ConfidenceBToA, p.Lift})
+	}
+	writeExport(w, format, pairs, "product-pairs",
+		[]string{"product_a_id", "product_a_name", "product_b_id", "product_b_name", "transactions", "support", "confidence_a_to_b", "confidence_b_to_a", "lift"}, rows)
+}
+
+// GetBoughtWith - GET /api/v1/reports/basket/{product_id}?days={days}&min_count={n}&limit={n}&format={json|csv|xlsx}
+func (h *ReportHandler) GetBoughtWith(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r, formatJSON)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+	productID, err := pathID(r, "product_id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid product ID")
+		return
+	}
+	days, err := intQuery(r, "days", 90)
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid days")
+		return
+	}
+	minCount, err := intQuery(r, "min_count", 2)
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid min_count")
+		return
+	}
+	limit, err := intQuery(r, "limit", 5)
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid limit")
+		return
+	}
+
+	products, err := h.service.GetBoughtWith(productID, days, minCount, limit)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	rows := make([][]interface{}, 0, len(products))
+	for _, p := range products {
+		rows = append(rows, []interface{}{p.ProductID, p.ProductName, p.Price, p.Stock, p.Transactions, p.Confidence, p.Lift})
+	}
+	writeExport(w, format, products, fmt.Sprintf("bought-with-%d", productID),
+		[]string{"product_id", "product_name", "price", "stock", "transactions", "confidence", "lift"}, rows)
+}
+
+// GetABCClassification - GET /api/v1/reports/abc?start_date={start_date}&end_date={end_date}&metric={revenue|gross_profit}&a={pct}&b={pct}&tz={timezone}&format={json|csv|xlsx}
+func (h *ReportHandler) GetABCClassification(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r, formatJSON)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+	thresholdA, err := floatQuery(r, "a", 80)
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid threshold a")
+		return
+	}
+	thresholdB, err := floatQuery(r, "b", 95)
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid threshold b")
+		return
+	}
+
+	report, err := h.service.GetABCClassification(
+		r.URL.Query().Get("start_date"),
+		r.URL.Query().Get("end_date"),
+		r.URL.Query().Get("tz"),
+		r.URL.Query().Get("metric"),
+		thresholdA,
+		thresholdB,
+	)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	rows := make([][]interface{}, 0, len(report.Products))
+	for _, p := range report.Products {
+		rows = append(rows, []interface{}{p.Rank, p.ProductID, p.ProductName, p.CategoryName, p.Quantity, p.Revenue, p.GrossProfit, p.SharePct, p.CumulativePct, p.Class})
+	}
+	writeExport(w, format, report, "abc-"+report.Metric,
+		[]string{"rank", "product_id", "product_name", "category_name", "quantity", "revenue", "gross_profit", "share_pct", "cumulative_pct", "class"}, rows)
+}
+
+// intQuery reads an integer query parameter, falling back to def when it is absent
+func intQuery(r *http.Request, key string, def int) (int, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
+
+// floatQuery reads a decimal query parameter, falling back to def when it is absent
+func floatQuery(r *http.Request, key string, def float64) (float64, error) {
+	value := r.URL.Query().Get(key)
+	if value == "" {
+		return def, nil
+	}
+	return strconv.ParseFloat(value, 64)
+}
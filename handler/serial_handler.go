@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"encoding/json"
+	"kasir-api/apperror"
+	"kasir-api/service"
+	"net/http"
+)
+
+type SerialHandler struct {
+	service *service.SerialService
+}
+
+func NewSerialHandler(service *service.SerialService) *SerialHandler {
+	return &SerialHandler{service: service}
+}
+
+// LookupSerial - GET /api/v1/serials/{serial_number}
+func (h *SerialHandler) LookupSerial(w http.ResponseWriter, r *http.Request) {
+	serialNumber := r.PathValue("serial_number")
+	if serialNumber == "" {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid serial number")
+		return
+	}
+
+	results, err := h.service.LookupSerial(serialNumber)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
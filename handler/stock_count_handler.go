@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"encoding/json"
+	"kasir-api/apperror"
+	"kasir-api/model"
+	"kasir-api/service"
+	"net/http"
+)
+
+type StockCountHandler struct {
+	service *service.StockCountService
+}
+
+func NewStockCountHandler(service *service.StockCountService) *StockCountHandler {
+	return &StockCountHandler{service: service}
+}
+
+// GetAllStockCounts - GET /api/v1/stock-counts
+func (h *StockCountHandler) GetAllStockCounts(w http.ResponseWriter, r *http.Request) {
+	counts, err := h.service.GetAllStockCounts()
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(counts)
+}
+
+func (h *StockCountHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var input model.StockCountInput
+	err := decodeJSON(r, &input)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	count, err := h.service.Create(&input)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(count)
+}
+
+// GetStockCountByID - GET /api/v1/stock-counts/{id}
+func (h *StockCountHandler) GetStockCountByID(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid stock count ID")
+		return
+	}
+
+	count, err := h.service.GetStockCountByID(id)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(count)
+}
+
+// AddEntries - POST /api/v1/stock-counts/{id}/entries
+func (h *StockCountHandler) AddEntries(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid stock count ID")
+		return
+	}
+
+	var input model.StockCountEntryInput
+	err = decodeJSON(r, &input)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	count, err := h.service.AddEntries(id, &input)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(count)
+}
+
+// GetVarianceReport - GET /api/v1/stock-counts/{id}/variance
+func (h *StockCountHandler) GetVarianceReport(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid stock count ID")
+		return
+	}
+
+	report, err := h.service.GetVarianceReport(id)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// Approve - POST /api/v1/stock-counts/{id}/approve
+func (h *StockCountHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid stock count ID")
+		return
+	}
+
+	var input model.StockCountApprovalInput
+	err = decodeJSON(r, &input)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	count, err := h.service.Approve(id, &input)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(count)
+}
+
+// Cancel - POST /api/v1/stock-counts/{id}/cancel
+func (h *StockCountHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid stock count ID")
+		return
+	}
+
+	count, err := h.service.Cancel(id)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(count)
+}
@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"encoding/json"
+	"kasir-api/apperror"
+	"kasir-api/model"
+	"kasir-api/service"
+	"net/http"
+)
+
+type SupplierHandler struct {
+	service *service.SupplierService
+}
+
+func NewSupplierHandler(service *service.SupplierService) *SupplierHandler {
+	return &SupplierHandler{service: service}
+}
+
+// GetAllSuppliers - GET /api/v1/suppliers
+func (h *SupplierHandler) GetAllSuppliers(w http.ResponseWriter, r *http.Request) {
+	suppliers, err := h.service.GetAllSuppliers()
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(suppliers)
+}
+
+func (h *SupplierHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var supplier model.Supplier
+	err := decodeJSON(r, &supplier)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	err = h.service.Create(&supplier)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(supplier)
+}
+
+// GetSupplierByID - GET /api/v1/suppliers/{id}
+func (h *SupplierHandler) GetSupplierByID(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid supplier ID")
+		return
+	}
+
+	supplier, err := h.service.GetSupplierByID(id)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(supplier)
+}
+
+// Update - PUT /api/v1/suppliers/{id}
+func (h *SupplierHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid supplier ID")
+		return
+	}
+
+	var supplier model.Supplier
+	err = decodeJSON(r, &supplier)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	supplier.ID = id
+	err = h.service.Update(&supplier)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(supplier)
+}
+
+// Delete - DELETE /api/v1/suppliers/{id}
+func (h *SupplierHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid supplier ID")
+		return
+	}
+
+	err = h.service.Delete(id)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Supplier deleted successfully",
+	})
+}
+
+// GetPurchaseHistory - GET /api/v1/suppliers/{id}/purchases
+func (h *SupplierHandler) GetPurchaseHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid supplier ID")
+		return
+	}
+
+	purchases, err := h.service.GetPurchaseHistory(id)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(purchases)
+}
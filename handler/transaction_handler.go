@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"kasir-api/apperror"
 	"kasir-api/model"
 	"kasir-api/service"
 	"net/http"
@@ -15,26 +16,17 @@ func NewTransactionHandler(service *service.TransactionService) *TransactionHand
 	return &TransactionHandler{service: service}
 }
 
-func (h *TransactionHandler) HandleCheckout(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodPost:
-		h.Checkout(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
-}
-
 func (h *TransactionHandler) Checkout(w http.ResponseWriter, r *http.Request) {
 	var request model.CheckoutRequest
-	err := json.NewDecoder(r.Body).Decode(&request)
+	err := decodeJSON(r, &request)
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apperror.Write(w, err)
 		return
 	}
 
-	transaction, err := h.service.Checkout(request.Items)
+	transaction, err := h.service.Checkout(&request)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		apperror.Write(w, err)
 		return
 	}
 
@@ -42,33 +34,118 @@ func (h *TransactionHandler) Checkout(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(transaction)
 }
 
-func (h *TransactionHandler) HandleTransactionsByDateRange(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodGet:
-		h.GetTransactionsByDateRange(w, r)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// GetTransactionByID - GET /api/v1/transactions/{id}
+func (h *TransactionHandler) GetTransactionByID(w http.ResponseWriter, r *http.Request) {
+	id, err := pathID(r, "id")
+	if err != nil {
+		apperror.WriteStatus(w, http.StatusBadRequest, "Invalid transaction ID")
+		return
+	}
+
+	transaction, err := h.service.GetTransactionByID(id)
+	if err != nil {
+		apperror.Write(w, err)
+		return
 	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(transaction)
 }
 
+// GetTransactionsByDateRange - GET /api/v1/reports/today, GET /api/v1/reports/sales?start_date={start_date}&end_date={end_date}
+// Without a date range it reports on the current day.
 func (h *TransactionHandler) GetTransactionsByDateRange(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r, formatJSON)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+
 	startDate := r.URL.Query().Get("start_date")
 	endDate := r.URL.Query().Get("end_date")
+	tz := r.URL.Query().Get("tz")
+	compare := r.URL.Query().Get("compare")
 
 	var report *model.TransactionReportRequest
-	var err error
 
 	if startDate == "" || endDate == "" {
-		report, err = h.service.GetTodayTransactions()
+		report, err = h.service.GetTodayTransactions(tz, compare)
 	} else {
-		report, err = h.service.GetTransactionsByDateRange(startDate, endDate)
+		report, err = h.service.GetTransactionsByDateRange(startDate, endDate, tz, compare)
 	}
 
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apperror.Write(w, err)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(report)
+	rows := make([][]interface{}, 0, len(report.Products)+1)
+	quantity := 0
+	for _, p := range report.Products {
+		quantity += p.Quantity
+		rows = append(rows, []interface{}{p.ProductID, p.Name, p.Quantity, p.Revenue, p.COGS, p.GrossProfit, p.GrossMarginPct})
+	}
+	rows = append(rows, []interface{}{"", "TOTAL", quantity, report.TotalRevenue, report.TotalCOGS, report.GrossProfit, report.GrossMarginPct})
+	writeExport(w, format, report, "report",
+		[]string{"product_id", "product_name", "quantity", "revenue", "cogs", "gross_profit", "gross_margin_pct"}, rows)
+}
+
+// GetTransactions - GET /api/v1/transactions?start_date={start_date}&end_date={end_date}&tz={timezone}&format={json|csv|xlsx}
+func (h *TransactionHandler) GetTransactions(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r, formatJSON)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+	startDate := r.URL.Query().Get("start_date")
+	endDate := r.URL.Query().Get("end_date")
+	tz := r.URL.Query().Get("tz")
+
+	if format == formatJSON {
+		transactions := make([]model.TransactionSummary, 0)
+		err := h.service.EachTransaction(startDate, endDate, tz, func(t model.TransactionSummary) error {
+			transactions = append(transactions, t)
+			return nil
+		})
+		if err != nil {
+			apperror.Write(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(transactions)
+		return
+	}
+
+	table := &lazyTable{w: w, format: format, filename: "transactions",
+		header: []string{"id", "created_at", "customer_name", "customer_phone", "item_count", "total_price"}}
+	err = h.service.EachTransaction(startDate, endDate, tz, func(t model.TransactionSummary) error {
+		return table.WriteRow(t.ID, t.CreatedAt, t.CustomerName, t.CustomerPhone, t.ItemCount, t.TotalPrice)
+	})
+	table.Finish(err)
+}
+
+// ExportTransactionLines streams every sold line of the date range; it defaults to CSV
+func (h *TransactionHandler) ExportTransactionLines(w http.ResponseWriter, r *http.Request) {
+	format, err := exportFormat(r, formatCSV)
+	if err != nil {
+		apperror.Write(w, err)
+		return
+	}
+	if format == formatJSON {
+		apperror.WriteStatus(w, http.StatusNotAcceptable, "Line items can only be exported as csv or xlsx")
+		return
+	}
+
+	table := &lazyTable{w: w, format: format, filename: "transaction-lines",
+		header: []string{"transaction_id", "created_at", "customer_name", "detail_id", "product_id", "product_name", "category",
+			"quantity", "subtotal", "unit_cost", "modifiers", "note"}}
+	err = h.service.EachTransactionLine(
+		r.URL.Query().Get("start_date"),
+		r.URL.Query().Get("end_date"),
+		r.URL.Query().Get("tz"),
+		func(l model.TransactionLine) error {
+			return table.WriteRow(l.TransactionID, l.CreatedAt, l.CustomerName, l.DetailID, l.ProductID, l.ProductName, l.CategoryName,
+				l.Quantity, l.Subtotal, l.UnitCost, l.Modifiers, l.Note)
+		})
+	table.Finish(err)
 }
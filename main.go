@@ -7,11 +7,14 @@ import (
 	"kasir-api/handler"
 	"kasir-api/middleware"
 	"kasir-api/repository"
+	"kasir-api/router"
 	"kasir-api/service"
 	"log"
 	"net/http"
 	"os"
 	"strings"
+	"time"
+	_ "time/tzdata"
 
 	"github.com/spf13/viper"
 )
@@ -20,28 +23,79 @@ type Config struct {
 	Port   string `mapstructure:"PORT"`
 	DBConn string `mapstructure:"DB_CONN"`
 	APIKey string `mapstructure:"APIKEY"`
+	// BusinessTZ is the timezone that decides which day a sale belongs to in reports
+	BusinessTZ string `mapstructure:"BUSINESS_TZ"`
 }
 
 func handleAPIInfo(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	apiInfo := map[string]interface{}{
-		"name":    "Kasir API",
-		"version": "3.0.0",
+		"name":       "Kasir API",
+		"version":    "4.0.0",
+		"base_path":  "/api/v1",
+		"deprecated": "The unversioned /api/produk, /api/categories, /api/report/... paths still work and answer with a Deprecation header",
 		"endpoints": []map[string]string{
 			{"method": "GET", "path": "/health", "description": "Health check"},
-			{"method": "GET", "path": "/api/produk", "description": "Get all products"},
-			{"method": "POST", "path": "/api/produk", "description": "Create new product"},
-			{"method": "GET", "path": "/api/produk/{id}", "description": "Get product by ID"},
-			{"method": "PUT", "path": "/api/produk/{id}", "description": "Update product by ID"},
-			{"method": "DELETE", "path": "/api/produk/{id}", "description": "Delete product by ID"},
-			{"method": "GET", "path": "/api/categories", "description": "Get all categories"},
-			{"method": "POST", "path": "/api/categories", "description": "Create new category"},
-			{"method": "GET", "path": "/api/categories/{id}", "description": "Get category by ID"},
-			{"method": "PUT", "path": "/api/categories/{id}", "description": "Update category by ID"},
-			{"method": "DELETE", "path": "/api/categories/{id}", "description": "Delete category by ID"},
-			{"method": "POST", "path": "/api/checkout", "description": "Checkout transaction"},
-			{"method": "GET", "path": "/api/report/hari-ini", "description": "Get today's transactions report"},
-			{"method": "GET", "path": "/api/report?start_date={start_date}&end_date={end_date}", "description": "Get transactions report by date range"},
+			{"method": "GET", "path": "/api/v1/products?name={name}&category_id={id}&min_price={price}&max_price={price}&in_stock={bool}&low_stock={bool}&sort={id|name|price|stock}&order={asc|desc}&limit={n}&offset={n}", "description": "Get products, filtered, sorted and paged (total in X-Total-Count)"},
+			{"method": "POST", "path": "/api/v1/products", "description": "Create new product"},
+			{"method": "GET", "path": "/api/v1/products/search?q={text}&limit={n}&boost_sales={bool}", "description": "Search products by name, SKU, barcode or category, typo-tolerant and ranked by relevance"},
+			{"method": "GET", "path": "/api/v1/products/low-stock", "description": "Get products at or below their minimum stock"},
+			{"method": "GET", "path": "/api/v1/products/{id}", "description": "Get product by ID"},
+			{"method": "PUT", "path": "/api/v1/products/{id}", "description": "Update product by ID"},
+			{"method": "DELETE", "path": "/api/v1/products/{id}", "description": "Delete product by ID"},
+			{"method": "GET", "path": "/api/v1/products/{id}/stock-history", "description": "Get stock movement ledger of a product"},
+			{"method": "POST", "path": "/api/v1/products/{id}/stock-adjustments", "description": "Adjust product stock with a reason"},
+			{"method": "GET", "path": "/api/v1/products/{id}/batches", "description": "Get batches of a batch-tracked product in FEFO order"},
+			{"method": "GET", "path": "/api/v1/categories?name={name}&sort={id|name}&order={asc|desc}&limit={n}&offset={n}", "description": "Get categories, sorted and paged (total in X-Total-Count)"},
+			{"method": "POST", "path": "/api/v1/categories", "description": "Create new category"},
+			{"method": "GET", "path": "/api/v1/categories/{id}", "description": "Get category by ID"},
+			{"method": "PUT", "path": "/api/v1/categories/{id}", "description": "Update category by ID"},
+			{"method": "DELETE", "path": "/api/v1/categories/{id}", "description": "Delete category by ID"},
+			{"method": "GET", "path": "/api/v1/suppliers", "description": "Get all suppliers"},
+			{"method": "POST", "path": "/api/v1/suppliers", "description": "Create new supplier"},
+			{"method": "GET", "path": "/api/v1/suppliers/{id}", "description": "Get supplier by ID"},
+			{"method": "PUT", "path": "/api/v1/suppliers/{id}", "description": "Update supplier by ID"},
+			{"method": "DELETE", "path": "/api/v1/suppliers/{id}", "description": "Delete supplier by ID"},
+			{"method": "GET", "path": "/api/v1/suppliers/{id}/purchases", "description": "Get received goods and unit costs paid to a supplier"},
+			{"method": "GET", "path": "/api/v1/purchase-orders?status={status}", "description": "Get all purchase orders"},
+			{"method": "POST", "path": "/api/v1/purchase-orders", "description": "Create new purchase order"},
+			{"method": "GET", "path": "/api/v1/purchase-orders/{id}", "description": "Get purchase order with its goods receipts"},
+			{"method": "POST", "path": "/api/v1/purchase-orders/{id}/receipts", "description": "Receive goods (full or partial delivery)"},
+			{"method": "POST", "path": "/api/v1/purchase-orders/{id}/cancel", "description": "Cancel purchase order"},
+			{"method": "GET", "path": "/api/v1/consignment/report?start_date={start_date}&end_date={end_date}&supplier_id={id}", "description": "Get unsettled consignment sales per supplier"},
+			{"method": "GET", "path": "/api/v1/consignment/settlements", "description": "Get consignment settlements"},
+			{"method": "POST", "path": "/api/v1/consignment/settlements", "description": "Create a settlement for a supplier and period"},
+			{"method": "GET", "path": "/api/v1/consignment/settlements/{id}", "description": "Get consignment settlement by ID"},
+			{"method": "POST", "path": "/api/v1/consignment/settlements/{id}/pay", "description": "Mark consignment settlement as paid"},
+			{"method": "GET", "path": "/api/v1/stock-counts", "description": "Get all stock count (opname) sessions"},
+			{"method": "POST", "path": "/api/v1/stock-counts", "description": "Start a stock count and snapshot system stock"},
+			{"method": "GET", "path": "/api/v1/stock-counts/{id}", "description": "Get stock count by ID"},
+			{"method": "POST", "path": "/api/v1/stock-counts/{id}/entries", "description": "Submit a batch of counted quantities"},
+			{"method": "GET", "path": "/api/v1/stock-counts/{id}/variance", "description": "Get variance report by product and value"},
+			{"method": "POST", "path": "/api/v1/stock-counts/{id}/approve", "description": "Approve stock count and post adjustments"},
+			{"method": "POST", "path": "/api/v1/stock-counts/{id}/cancel", "description": "Cancel stock count"},
+			{"method": "GET", "path": "/api/v1/modifiers", "description": "Get all modifier groups (filter with ?product_id={id})"},
+			{"method": "POST", "path": "/api/v1/modifiers", "description": "Create new modifier group"},
+			{"method": "GET", "path": "/api/v1/modifiers/{id}", "description": "Get modifier group by ID"},
+			{"method": "PUT", "path": "/api/v1/modifiers/{id}", "description": "Update modifier group by ID"},
+			{"method": "DELETE", "path": "/api/v1/modifiers/{id}", "description": "Delete modifier group by ID"},
+			{"method": "POST", "path": "/api/v1/checkout", "description": "Checkout transaction"},
+			{"method": "GET", "path": "/api/v1/transactions?start_date={start_date}&end_date={end_date}&tz={timezone}&format={json|csv|xlsx}", "description": "List transactions of a date range"},
+			{"method": "GET", "path": "/api/v1/transactions/lines?start_date={start_date}&end_date={end_date}&tz={timezone}&format={csv|xlsx}", "description": "Stream every sold line of a date range as CSV or XLSX"},
+			{"method": "GET", "path": "/api/v1/transactions/{id}", "description": "Get transaction by ID (receipt / kitchen ticket)"},
+			{"method": "GET", "path": "/api/v1/serials/{serial_number}", "description": "Look up a serial number and the transaction it was sold in"},
+			{"method": "GET", "path": "/api/v1/reports/today?tz={timezone}&compare={previous|last_year}&format={json|csv|xlsx}", "description": "Get today's transactions report"},
+			{"method": "GET", "path": "/api/v1/reports/sales?start_date={start_date}&end_date={end_date}&tz={timezone}&compare={previous|last_year}&format={json|csv|xlsx}", "description": "Get transactions report by date range"},
+			{"method": "GET", "path": "/api/v1/reports/products?start_date={start_date}&end_date={end_date}&limit={n}&sort={quantity|revenue|transactions}&category_id={id}&tz={timezone}&format={json|csv|xlsx}", "description": "Get ranked sales per product"},
+			{"method": "GET", "path": "/api/v1/reports/timeseries?start_date={start_date}&end_date={end_date}&interval={hour|day|week|month}&tz={timezone}&format={json|csv|xlsx}", "description": "Get revenue and transaction count per time bucket"},
+			{"method": "GET", "path": "/api/v1/reports/categories?start_date={start_date}&end_date={end_date}&tz={timezone}&format={json|csv|xlsx}", "description": "Get sales per category compared with the previous period"},
+			{"method": "GET", "path": "/api/v1/reports/slow-movers?days={days}&threshold={qty}&sort={stock_value|days_since_last_sale|quantity_sold|stock}&format={json|csv|xlsx}", "description": "Get dead stock and slow-moving products"},
+			{"method": "GET", "path": "/api/v1/reports/inventory-valuation?as_of={date}&tz={timezone}&format={json|csv|xlsx}", "description": "Get stock value per product and category, now or at the end of a past date"},
+			{"method": "GET", "path": "/api/v1/reports/basket?start_date={start_date}&end_date={end_date}&min_count={n}&limit={n}&tz={timezone}&format={json|csv|xlsx}", "description": "Get product pairs bought together with support, confidence and lift"},
+			{"method": "GET", "path": "/api/v1/reports/basket/{product_id}?days={days}&min_count={n}&limit={n}&format={json|csv|xlsx}", "description": "Get products often bought with a product (add-on suggestions)"},
+			{"method": "GET", "path": "/api/v1/reports/abc?start_date={start_date}&end_date={end_date}&metric={revenue|gross_profit}&a={pct}&b={pct}&tz={timezone}&format={json|csv|xlsx}", "description": "Get ABC classification of products by contribution"},
+			{"method": "GET", "path": "/api/v1/reports/reorder?days={days}&cover_days={cover_days}&format={json|csv|xlsx}", "description": "Get reorder suggestions from recent sales velocity"},
+			{"method": "GET", "path": "/api/v1/reports/expiring?days={days}&format={json|csv|xlsx}", "description": "Get batches expiring within the given days"},
 		},
 	}
 	json.NewEncoder(w).Encode(apiInfo)
@@ -60,9 +114,18 @@ func main() {
 	}
 
 	config := Config{
-		Port:   viper.GetString("PORT"),
-		DBConn: viper.GetString("DB_CONN"),
-		APIKey: viper.GetString("APIKEY"),
+		Port:       viper.GetString("PORT"),
+		DBConn:     viper.GetString("DB_CONN"),
+		APIKey:     viper.GetString("APIKEY"),
+		BusinessTZ: viper.GetString("BUSINESS_TZ"),
+	}
+	if config.BusinessTZ == "" {
+		config.BusinessTZ = "Asia/Jakarta"
+	}
+
+	businessLocation, err := time.LoadLocation(config.BusinessTZ)
+	if err != nil {
+		log.Fatal("Invalid BUSINESS_TZ:", err)
 	}
 
 	// Debug: Print loaded config
@@ -79,32 +142,140 @@ func main() {
 
 	apiKeyMiddleware := middleware.APIKey(config.APIKey)
 
+	stockRepo := repository.NewStockRepository(db)
+	batchRepo := repository.NewBatchRepository(db)
+	stockService := service.NewStockService(stockRepo, batchRepo)
+
 	productRepo := repository.NewProductRepository(db)
-	productService := service.NewProductService(productRepo)
-	productHandler := handler.NewProductHandler(productService)
+	productService := service.NewProductService(productRepo, businessLocation)
+	productHandler := handler.NewProductHandler(productService, stockService)
 
 	categoryRepo := repository.NewCategoryRepository(db)
 	categoryService := service.NewCategoryService(categoryRepo)
 	categoryHandler := handler.NewCategoryHandler(categoryService)
 
+	supplierRepo := repository.NewSupplierRepository(db)
+	supplierService := service.NewSupplierService(supplierRepo)
+	supplierHandler := handler.NewSupplierHandler(supplierService)
+
+	purchaseOrderRepo := repository.NewPurchaseOrderRepository(db)
+	purchaseOrderService := service.NewPurchaseOrderService(purchaseOrderRepo)
+	purchaseOrderHandler := handler.NewPurchaseOrderHandler(purchaseOrderService)
+
+	consignmentRepo := repository.NewConsignmentRepository(db)
+	consignmentService := service.NewConsignmentService(consignmentRepo, businessLocation)
+	consignmentHandler := handler.NewConsignmentHandler(consignmentService)
+
+	stockCountRepo := repository.NewStockCountRepository(db)
+	stockCountService := service.NewStockCountService(stockCountRepo)
+	stockCountHandler := handler.NewStockCountHandler(stockCountService)
+
+	modifierRepo := repository.NewModifierRepository(db)
+	modifierService := service.NewModifierService(modifierRepo)
+	modifierHandler := handler.NewModifierHandler(modifierService)
+
 	transactionRepo := repository.NewTransactionRepository(db)
-	transactionService := service.NewTransactionService(transactionRepo)
+	transactionService := service.NewTransactionService(transactionRepo, businessLocation)
 	transactionHandler := handler.NewTransactionHandler(transactionService)
 
-	// setup routes
-	http.HandleFunc("/", handleAPIInfo)
-	http.HandleFunc("/api/produk", middleware.CORS(middleware.Logger(productHandler.HandleProducts)))
-	http.HandleFunc("/api/produk/", middleware.CORS(middleware.Logger(apiKeyMiddleware(productHandler.HandleProductByID))))
+	// go run main.go rebuild-daily-sales [start_date end_date]
+	if len(os.Args) > 1 && os.Args[1] == "rebuild-daily-sales" {
+		var startDate, endDate string
+		if len(os.Args) > 3 {
+			startDate, endDate = os.Args[2], os.Args[3]
+		}
+		days, err := transactionService.RebuildDailySales(startDate, endDate)
+		if err != nil {
+			log.Fatal("Failed to rebuild daily sales:", err)
+		}
+		fmt.Printf("Rebuilt daily sales for %d days with sales\n", days)
+		return
+	}
+
+	serialRepo := repository.NewSerialRepository(db)
+	serialService := service.NewSerialService(serialRepo, transactionRepo)
+	serialHandler := handler.NewSerialHandler(serialService)
+
+	reportRepo := repository.NewReportRepository(db)
+	reportService := service.NewReportService(reportRepo, businessLocation)
+	reportHandler := handler.NewReportHandler(reportService)
+
+	// setup routes: every resource lives under /api/v1; the older unversioned paths are
+	// kept as aliases that answer with a Deprecation header
+	mux := router.New(middleware.CORS, middleware.Logger)
+	mux.Handle(http.MethodGet, "/{$}", handleAPIInfo)
+
+	mux.Handle(http.MethodGet, "/api/v1/products", productHandler.GetAllProducts, "/api/produk")
+	mux.Handle(http.MethodPost, "/api/v1/products", productHandler.Create, "/api/produk")
+	mux.Handle(http.MethodGet, "/api/v1/products/search", productHandler.SearchProducts)
+	mux.Handle(http.MethodGet, "/api/v1/products/low-stock", apiKeyMiddleware(productHandler.GetLowStockProducts), "/api/produk/low-stock")
+	mux.Handle(http.MethodGet, "/api/v1/products/{id}", apiKeyMiddleware(productHandler.GetProductByID), "/api/produk/{id}")
+	mux.Handle(http.MethodPut, "/api/v1/products/{id}", apiKeyMiddleware(productHandler.Update), "/api/produk/{id}")
+	mux.Handle(http.MethodDelete, "/api/v1/products/{id}", apiKeyMiddleware(productHandler.Delete), "/api/produk/{id}")
+	mux.Handle(http.MethodGet, "/api/v1/products/{id}/stock-history", apiKeyMiddleware(productHandler.GetStockHistory), "/api/produk/{id}/stock-history")
+	mux.Handle(http.MethodPost, "/api/v1/products/{id}/stock-adjustments", apiKeyMiddleware(productHandler.AdjustStock), "/api/produk/{id}/stock-adjustments")
+	mux.Handle(http.MethodGet, "/api/v1/products/{id}/batches", apiKeyMiddleware(productHandler.GetBatches), "/api/produk/{id}/batches")
+
+	mux.Handle(http.MethodGet, "/api/v1/categories", categoryHandler.GetAllCategories, "/api/categories")
+	mux.Handle(http.MethodPost, "/api/v1/categories", categoryHandler.Create, "/api/categories")
+	mux.Handle(http.MethodGet, "/api/v1/categories/{id}", apiKeyMiddleware(categoryHandler.GetCategoryByID), "/api/categories/{id}")
+	mux.Handle(http.MethodPut, "/api/v1/categories/{id}", apiKeyMiddleware(categoryHandler.Update), "/api/categories/{id}")
+	mux.Handle(http.MethodDelete, "/api/v1/categories/{id}", apiKeyMiddleware(categoryHandler.Delete), "/api/categories/{id}")
+
+	mux.Handle(http.MethodGet, "/api/v1/suppliers", apiKeyMiddleware(supplierHandler.GetAllSuppliers), "/api/suppliers")
+	mux.Handle(http.MethodPost, "/api/v1/suppliers", apiKeyMiddleware(supplierHandler.Create), "/api/suppliers")
+	mux.Handle(http.MethodGet, "/api/v1/suppliers/{id}", apiKeyMiddleware(supplierHandler.GetSupplierByID), "/api/suppliers/{id}")
+	mux.Handle(http.MethodPut, "/api/v1/suppliers/{id}", apiKeyMiddleware(supplierHandler.Update), "/api/suppliers/{id}")
+	mux.Handle(http.MethodDelete, "/api/v1/suppliers/{id}", apiKeyMiddleware(supplierHandler.Delete), "/api/suppliers/{id}")
+	mux.Handle(http.MethodGet, "/api/v1/suppliers/{id}/purchases", apiKeyMiddleware(supplierHandler.GetPurchaseHistory), "/api/suppliers/{id}/purchases")
+
+	mux.Handle(http.MethodGet, "/api/v1/purchase-orders", apiKeyMiddleware(purchaseOrderHandler.GetAllPurchaseOrders), "/api/purchase-orders")
+	mux.Handle(http.MethodPost, "/api/v1/purchase-orders", apiKeyMiddleware(purchaseOrderHandler.Create), "/api/purchase-orders")
+	mux.Handle(http.MethodGet, "/api/v1/purchase-orders/{id}", apiKeyMiddleware(purchaseOrderHandler.GetPurchaseOrderByID), "/api/purchase-orders/{id}")
+	mux.Handle(http.MethodPost, "/api/v1/purchase-orders/{id}/receipts", apiKeyMiddleware(purchaseOrderHandler.Receive), "/api/purchase-orders/{id}/receipts")
+	mux.Handle(http.MethodPost, "/api/v1/purchase-orders/{id}/cancel", apiKeyMiddleware(purchaseOrderHandler.Cancel), "/api/purchase-orders/{id}/cancel")
+
+	mux.Handle(http.MethodGet, "/api/v1/consignment/report", apiKeyMiddleware(consignmentHandler.GetConsignmentReport), "/api/consignment/report")
+	mux.Handle(http.MethodGet, "/api/v1/consignment/settlements", apiKeyMiddleware(consignmentHandler.GetAllSettlements), "/api/consignment/settlements")
+	mux.Handle(http.MethodPost, "/api/v1/consignment/settlements", apiKeyMiddleware(consignmentHandler.CreateSettlement), "/api/consignment/settlements")
+	mux.Handle(http.MethodGet, "/api/v1/consignment/settlements/{id}", apiKeyMiddleware(consignmentHandler.GetSettlementByID), "/api/consignment/settlements/{id}")
+	mux.Handle(http.MethodPost, "/api/v1/consignment/settlements/{id}/pay", apiKeyMiddleware(consignmentHandler.MarkPaid), "/api/consignment/settlements/{id}/pay")
+
+	mux.Handle(http.MethodGet, "/api/v1/stock-counts", apiKeyMiddleware(stockCountHandler.GetAllStockCounts), "/api/stock-counts")
+	mux.Handle(http.MethodPost, "/api/v1/stock-counts", apiKeyMiddleware(stockCountHandler.Create), "/api/stock-counts")
+	mux.Handle(http.MethodGet, "/api/v1/stock-counts/{id}", apiKeyMiddleware(stockCountHandler.GetStockCountByID), "/api/stock-counts/{id}")
+	mux.Handle(http.MethodPost, "/api/v1/stock-counts/{id}/entries", apiKeyMiddleware(stockCountHandler.AddEntries), "/api/stock-counts/{id}/entries")
+	mux.Handle(http.MethodGet, "/api/v1/stock-counts/{id}/variance", apiKeyMiddleware(stockCountHandler.GetVarianceReport), "/api/stock-counts/{id}/variance")
+	mux.Handle(http.MethodPost, "/api/v1/stock-counts/{id}/approve", apiKeyMiddleware(stockCountHandler.Approve), "/api/stock-counts/{id}/approve")
+	mux.Handle(http.MethodPost, "/api/v1/stock-counts/{id}/cancel", apiKeyMiddleware(stockCountHandler.Cancel), "/api/stock-counts/{id}/cancel")
+
+	mux.Handle(http.MethodGet, "/api/v1/modifiers", modifierHandler.GetAllModifierGroups, "/api/modifiers")
+	mux.Handle(http.MethodPost, "/api/v1/modifiers", apiKeyMiddleware(modifierHandler.Create), "/api/modifiers")
+	mux.Handle(http.MethodGet, "/api/v1/modifiers/{id}", apiKeyMiddleware(modifierHandler.GetModifierGroupByID), "/api/modifiers/{id}")
+	mux.Handle(http.MethodPut, "/api/v1/modifiers/{id}", apiKeyMiddleware(modifierHandler.Update), "/api/modifiers/{id}")
+	mux.Handle(http.MethodDelete, "/api/v1/modifiers/{id}", apiKeyMiddleware(modifierHandler.Delete), "/api/modifiers/{id}")
 
-	http.HandleFunc("/api/categories", middleware.CORS(middleware.Logger(categoryHandler.HandleCategories)))
-	http.HandleFunc("/api/categories/", middleware.CORS(middleware.Logger(apiKeyMiddleware(categoryHandler.HandleCategoryByID))))
+	mux.Handle(http.MethodPost, "/api/v1/checkout", apiKeyMiddleware(transactionHandler.Checkout), "/api/checkout")
+	mux.Handle(http.MethodGet, "/api/v1/transactions", apiKeyMiddleware(transactionHandler.GetTransactions), "/api/transactions")
+	mux.Handle(http.MethodGet, "/api/v1/transactions/lines", apiKeyMiddleware(transactionHandler.ExportTransactionLines), "/api/transactions/lines")
+	mux.Handle(http.MethodGet, "/api/v1/transactions/{id}", apiKeyMiddleware(transactionHandler.GetTransactionByID), "/api/transactions/{id}")
+	mux.Handle(http.MethodGet, "/api/v1/serials/{serial_number}", apiKeyMiddleware(serialHandler.LookupSerial), "/api/serials/{serial_number}")
 
-	http.HandleFunc("/api/checkout", middleware.CORS(middleware.Logger(apiKeyMiddleware(transactionHandler.HandleCheckout))))
-	http.HandleFunc("/api/report/hari-ini", middleware.CORS(middleware.Logger(transactionHandler.HandleTransactionsByDateRange)))
-	http.HandleFunc("/api/report", middleware.CORS(middleware.Logger(transactionHandler.HandleTransactionsByDateRange)))
+	mux.Handle(http.MethodGet, "/api/v1/reports/today", transactionHandler.GetTransactionsByDateRange, "/api/report/hari-ini")
+	mux.Handle(http.MethodGet, "/api/v1/reports/sales", transactionHandler.GetTransactionsByDateRange, "/api/report")
+	mux.Handle(http.MethodGet, "/api/v1/reports/products", reportHandler.GetProductSales, "/api/report/products")
+	mux.Handle(http.MethodGet, "/api/v1/reports/timeseries", reportHandler.GetSalesTimeSeries, "/api/report/timeseries")
+	mux.Handle(http.MethodGet, "/api/v1/reports/categories", reportHandler.GetCategorySales, "/api/report/categories")
+	mux.Handle(http.MethodGet, "/api/v1/reports/slow-movers", reportHandler.GetSlowMovers, "/api/report/slow-movers")
+	mux.Handle(http.MethodGet, "/api/v1/reports/inventory-valuation", reportHandler.GetInventoryValuation, "/api/report/inventory-valuation")
+	mux.Handle(http.MethodGet, "/api/v1/reports/basket", reportHandler.GetProductPairs, "/api/report/basket")
+	mux.Handle(http.MethodGet, "/api/v1/reports/basket/{product_id}", reportHandler.GetBoughtWith, "/api/report/basket/{product_id}")
+	mux.Handle(http.MethodGet, "/api/v1/reports/abc", reportHandler.GetABCClassification, "/api/report/abc")
+	mux.Handle(http.MethodGet, "/api/v1/reports/reorder", reportHandler.GetReorderSuggestions, "/api/report/reorder")
+	mux.Handle(http.MethodGet, "/api/v1/reports/expiring", reportHandler.GetExpiringBatches, "/api/report/expiring")
 
 	// localhost:8080/health
-	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle(http.MethodGet, "/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
 			"status":  "OK",
@@ -114,7 +285,7 @@ func main() {
 	})
 	fmt.Println("Starting server at localhost:" + config.Port)
 
-	err = http.ListenAndServe(":"+config.Port, nil)
+	err = http.ListenAndServe(":"+config.Port, mux)
 	if err != nil {
 		fmt.Println("Server failed to start")
 	}
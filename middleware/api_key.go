@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"fmt"
+	"kasir-api/apperror"
 	"net/http"
 )
 
@@ -20,12 +21,12 @@ func APIKey(validApiKey string) func(http.HandlerFunc) http.HandlerFunc {
 			fmt.Printf("====================\n")
 
 			if apiKey == "" {
-				http.Error(w, "API Key required", http.StatusUnauthorized)
+				apperror.WriteStatus(w, http.StatusUnauthorized, "API Key required")
 				return
 			}
 
 			if apiKey != validApiKey {
-				http.Error(w, "Invalid API Key", http.StatusUnauthorized)
+				apperror.WriteStatus(w, http.StatusUnauthorized, "Invalid API Key")
 				return
 			}
 
@@ -7,6 +7,7 @@ func CORS(next http.HandlerFunc) http.HandlerFunc {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Method", "GET, POST, PUT, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "X-API-Key, Content-Type")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Total-Count, Deprecation, Link")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
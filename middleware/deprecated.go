@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Deprecated marks a response as served by a deprecated path and links to its successor,
+// a route pattern whose {name} parameters are filled in from the current request
+func Deprecated(successor string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="successor-version"`, expandPath(successor, r)))
+			next(w, r)
+		}
+	}
+}
+
+func expandPath(pattern string, r *http.Request) string {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = url.PathEscape(r.PathValue(strings.Trim(segment, "{}")))
+		}
+	}
+	return strings.Join(segments, "/")
+}
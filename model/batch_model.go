@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+type ProductBatch struct {
+	ID          int        `json:"id"`
+	ProductID   int        `json:"product_id"`
+	ProductName string     `json:"product_name,omitempty"`
+	LotNumber   string     `json:"lot_number"`
+	ExpiryDate  *time.Time `json:"expiry_date"`
+	Quantity    int        `json:"quantity"`
+	ReceivedAt  time.Time  `json:"received_at"`
+}
+
+// BatchInput identifies the batch a stock change applies to. Either BatchID refers to an existing batch,
+// or LotNumber/ExpiryDate (YYYY-MM-DD) describe a new one for incoming stock.
+type BatchInput struct {
+	BatchID    int    `json:"batch_id"`
+	LotNumber  string `json:"lot_number"`
+	ExpiryDate string `json:"expiry_date"`
+}
+
+type ExpiringBatch struct {
+	ProductBatch
+	DaysUntilExpiry int     `json:"days_until_expiry"`
+	Expired         bool    `json:"expired"`
+	StockValue      float64 `json:"stock_value"`
+}
@@ -5,3 +5,9 @@ type Category struct {
 	Category    string `json:"category"`
 	Description string `json:"description"`
 }
+
+// CategoryFilter narrows GET /api/v1/categories; zero values do not filter
+type CategoryFilter struct {
+	Name string
+	ListOptions
+}
@@ -0,0 +1,60 @@
+package model
+
+import "time"
+
+const (
+	ConsignmentSettlementUnpaid = "unpaid"
+	ConsignmentSettlementPaid   = "paid"
+)
+
+// ConsignmentReport lists consigned goods sold in a period that are not yet part of a settlement
+type ConsignmentReport struct {
+	StartDate   string                       `json:"start_date"`
+	EndDate     string                       `json:"end_date"`
+	TotalPayout float64                      `json:"total_payout"`
+	Suppliers   []ConsignmentSupplierSummary `json:"suppliers"`
+}
+
+type ConsignmentSupplierSummary struct {
+	SupplierID    int                      `json:"supplier_id"`
+	SupplierName  string                   `json:"supplier_name"`
+	TotalQuantity int                      `json:"total_quantity"`
+	TotalRevenue  float64                  `json:"total_revenue"`
+	TotalPayout   float64                  `json:"total_payout"`
+	Products      []ConsignmentProductLine `json:"products"`
+}
+
+type ConsignmentProductLine struct {
+	ProductID   int     `json:"product_id"`
+	ProductName string  `json:"product_name"`
+	Quantity    int     `json:"quantity"`
+	Revenue     float64 `json:"revenue"`
+	Payout      float64 `json:"payout"`
+}
+
+type ConsignmentSettlement struct {
+	ID               int                      `json:"id"`
+	SupplierID       int                      `json:"supplier_id"`
+	SupplierName     string                   `json:"supplier_name"`
+	PeriodStart      time.Time                `json:"period_start"`
+	PeriodEnd        time.Time                `json:"period_end"`
+	TotalQuantity    int                      `json:"total_quantity"`
+	TotalPayout      float64                  `json:"total_payout"`
+	Status           string                   `json:"status"`
+	CreatedAt        time.Time                `json:"created_at"`
+	PaidAt           *time.Time               `json:"paid_at,omitempty"`
+	PaidBy           string                   `json:"paid_by,omitempty"`
+	PaymentReference string                   `json:"payment_reference,omitempty"`
+	Products         []ConsignmentProductLine `json:"products,omitempty"`
+}
+
+type ConsignmentSettlementInput struct {
+	SupplierID int    `json:"supplier_id"`
+	StartDate  string `json:"start_date"`
+	EndDate    string `json:"end_date"`
+}
+
+type ConsignmentPaymentInput struct {
+	Actor     string `json:"actor"`
+	Reference string `json:"reference"`
+}
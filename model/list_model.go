@@ -0,0 +1,9 @@
+package model
+
+// ListOptions sorts and pages a list endpoint
+type ListOptions struct {
+	SortBy string // field to sort by, empty = id
+	Order  string // asc or desc, empty = asc
+	Limit  int    // 0 = default page size of 50
+	Offset int
+}
@@ -0,0 +1,18 @@
+package model
+
+type ModifierGroup struct {
+	ID         int              `json:"id"`
+	Name       string           `json:"name"`
+	Required   bool             `json:"required"`
+	MinSelect  int              `json:"min_select"`
+	MaxSelect  int              `json:"max_select"` // 0 = unlimited
+	Options    []ModifierOption `json:"options"`
+	ProductIDs []int            `json:"product_ids"`
+}
+
+type ModifierOption struct {
+	ID         int     `json:"id"`
+	GroupID    int     `json:"group_id"`
+	Name       string  `json:"name"`
+	PriceDelta float64 `json:"price_delta"`
+}
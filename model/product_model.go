@@ -1,16 +1,60 @@
 package model
 
 type Product struct {
-	ID       int      `json:"id"`
-	Name     string   `json:"name"`
-	Price    float64  `json:"price"`
-	Stock    int      `json:"stock"`
-	Category Category `json:"category"`
+	ID                int      `json:"id"`
+	Name              string   `json:"name"`
+	SKU               string   `json:"sku"`
+	Barcode           string   `json:"barcode"`
+	Price             float64  `json:"price"`
+	CostPrice         float64  `json:"cost_price"`
+	Stock             int      `json:"stock"`
+	MinStock          int      `json:"min_stock"`
+	ReorderQty        int      `json:"reorder_qty"`
+	BatchTracked      bool     `json:"batch_tracked"`
+	SerialTracked     bool     `json:"serial_tracked"`
+	ConsignorID       *int     `json:"consignor_id"` // supplier who consigned the product, null for own stock
+	ConsignmentPayout float64  `json:"consignment_payout"`
+	Category          Category `json:"category"`
 }
 
 type ProductInput struct {
-	Name        string  `json:"name"`
-	Price       float64 `json:"price"`
-	Stock       int     `json:"stock"`
-	Category_ID int     `json:"category_id"`
+	Name              string   `json:"name"`
+	SKU               string   `json:"sku"`     // optional, unique ignoring case
+	Barcode           string   `json:"barcode"` // optional, unique
+	Price             float64  `json:"price"`
+	CostPrice         *float64 `json:"cost_price"` // optional starting cost; goods receipts keep it as a weighted average, update leaves it when omitted
+	Stock             int      `json:"stock"`      // initial stock, rejected on update
+	MinStock          int      `json:"min_stock"`
+	ReorderQty        int      `json:"reorder_qty"`
+	BatchTracked      *bool    `json:"batch_tracked"` // update leaves batch tracking as it is when omitted
+	SerialTracked     bool     `json:"serial_tracked"`
+	ConsignorID       *int     `json:"consignor_id"`
+	ConsignmentPayout float64  `json:"consignment_payout"` // paid to the consignor per unit sold
+	Category_ID       int      `json:"category_id"`
+}
+
+// ProductFilter narrows GET /api/v1/products; zero values do not filter
+type ProductFilter struct {
+	Name       string
+	CategoryID int
+	MinPrice   *float64
+	MaxPrice   *float64
+	InStock    bool // only products with stock left
+	LowStock   bool // only products at or below their minimum stock
+	ListOptions
+}
+
+// ProductSearchResult is a product matched by a search with its relevance, highest first
+type ProductSearchResult struct {
+	Product
+	Score float64 `json:"score"`
+}
+
+// ProductSearch is a search-as-you-type query over name, SKU, barcode and category
+type ProductSearch struct {
+	Query      string
+	TSQuery    string // prefix full-text query built from the words of Query
+	Limit      int
+	BoostSales bool   // rank recently sold products higher
+	SalesSince string // first business day of the sales that boost, YYYY-MM-DD
 }
@@ -0,0 +1,78 @@
+package model
+
+import "time"
+
+const (
+	PurchaseOrderOpen      = "open"
+	PurchaseOrderPartial   = "partial"
+	PurchaseOrderReceived  = "received"
+	PurchaseOrderCancelled = "cancelled"
+)
+
+type PurchaseOrder struct {
+	ID           int                 `json:"id"`
+	SupplierID   int                 `json:"supplier_id"`
+	SupplierName string              `json:"supplier_name,omitempty"`
+	Status       string              `json:"status"`
+	Note         string              `json:"note"`
+	CreatedAt    time.Time           `json:"created_at"`
+	Lines        []PurchaseOrderLine `json:"lines"`
+	Receipts     []GoodsReceipt      `json:"receipts,omitempty"`
+}
+
+type PurchaseOrderLine struct {
+	ID               int     `json:"id"`
+	PurchaseOrderID  int     `json:"purchase_order_id"`
+	ProductID        int     `json:"product_id"`
+	ProductName      string  `json:"product_name,omitempty"`
+	QuantityOrdered  int     `json:"quantity_ordered"`
+	QuantityReceived int     `json:"quantity_received"`
+	ExpectedCost     float64 `json:"expected_cost"`
+}
+
+type PurchaseOrderInput struct {
+	SupplierID int                      `json:"supplier_id"`
+	Note       string                   `json:"note"`
+	Lines      []PurchaseOrderLineInput `json:"lines"`
+}
+
+type PurchaseOrderLineInput struct {
+	ProductID    int     `json:"product_id"`
+	Quantity     int     `json:"quantity"`
+	ExpectedCost float64 `json:"expected_cost"`
+}
+
+type GoodsReceipt struct {
+	ID              int                `json:"id"`
+	PurchaseOrderID int                `json:"purchase_order_id"`
+	Note            string             `json:"note"`
+	Actor           string             `json:"actor"`
+	ReceivedAt      time.Time          `json:"received_at"`
+	Lines           []GoodsReceiptLine `json:"lines"`
+}
+
+type GoodsReceiptLine struct {
+	ID                  int     `json:"id"`
+	GoodsReceiptID      int     `json:"goods_receipt_id"`
+	PurchaseOrderLineID int     `json:"purchase_order_line_id"`
+	ProductID           int     `json:"product_id"`
+	ProductName         string  `json:"product_name,omitempty"`
+	Quantity            int     `json:"quantity"`
+	UnitCost            float64 `json:"unit_cost"`
+	BatchID             int     `json:"batch_id,omitempty"`
+}
+
+type GoodsReceiptInput struct {
+	Note  string                  `json:"note"`
+	Actor string                  `json:"actor"`
+	Lines []GoodsReceiptLineInput `json:"lines"`
+}
+
+type GoodsReceiptLineInput struct {
+	PurchaseOrderLineID int      `json:"purchase_order_line_id"`
+	Quantity            int      `json:"quantity"`
+	UnitCost            float64  `json:"unit_cost"`      // defaults to the line's expected cost
+	LotNumber           string   `json:"lot_number"`     // required for batch-tracked products
+	ExpiryDate          string   `json:"expiry_date"`    // YYYY-MM-DD
+	SerialNumbers       []string `json:"serial_numbers"` // required for serial-tracked products, one per unit
+}
@@ -0,0 +1,167 @@
+package model
+
+import "time"
+
+type ReorderSuggestion struct {
+	ProductID     int      `json:"product_id"`
+	ProductName   string   `json:"product_name"`
+	Stock         int      `json:"stock"`
+	MinStock      int      `json:"min_stock"`
+	ReorderQty    int      `json:"reorder_qty"`
+	QuantitySold  int      `json:"quantity_sold"`
+	AvgDailySales float64  `json:"avg_daily_sales"`
+	DaysOfCover   *float64 `json:"days_of_cover"` // null when the product has not sold in the period
+	SuggestedQty  int      `json:"suggested_qty"`
+}
+
+type ProductSales struct {
+	Rank             int     `json:"rank"`
+	ProductID        int     `json:"product_id"`
+	ProductName      string  `json:"product_name"`
+	CategoryID       int     `json:"category_id"`
+	CategoryName     string  `json:"category_name"`
+	Quantity         int     `json:"quantity"`
+	Revenue          float64 `json:"revenue"`
+	TransactionCount int     `json:"transaction_count"`
+}
+
+type ProductSalesFilter struct {
+	StartDate  string
+	EndDate    string
+	SortBy     string // quantity, revenue or transactions
+	CategoryID int    // 0 = all categories
+	Limit      int    // 0 = no limit
+	Timezone   string // empty = business timezone
+	From       time.Time
+	To         time.Time // exclusive, the start of the day after EndDate
+	Daily      bool      // read the daily rollups of StartDate to EndDate instead
+}
+
+type SalesTimeSeries struct {
+	StartDate string        `json:"start_date"`
+	EndDate   string        `json:"end_date"`
+	Interval  string        `json:"interval"`
+	Buckets   []SalesBucket `json:"buckets"`
+}
+
+type SalesBucket struct {
+	Start            time.Time `json:"start"`
+	Revenue          float64   `json:"revenue"`
+	TransactionCount int       `json:"transaction_count"`
+}
+
+type CategorySalesReport struct {
+	StartDate            string          `json:"start_date"`
+	EndDate              string          `json:"end_date"`
+	PreviousStartDate    string          `json:"previous_start_date"`
+	PreviousEndDate      string          `json:"previous_end_date"`
+	TotalRevenue         float64         `json:"total_revenue"`
+	PreviousTotalRevenue float64         `json:"previous_total_revenue"`
+	Categories           []CategorySales `json:"categories"`
+}
+
+type CategorySales struct {
+	CategoryID       int      `json:"category_id"`
+	CategoryName     string   `json:"category_name"`
+	Quantity         int      `json:"quantity"`
+	Revenue          float64  `json:"revenue"`
+	SharePct         float64  `json:"share_pct"`
+	PreviousQuantity int      `json:"previous_quantity"`
+	PreviousRevenue  float64  `json:"previous_revenue"`
+	RevenueChangePct *float64 `json:"revenue_change_pct"` // null when the previous period had no revenue
+}
+
+// SlowMover is a product in stock that sold at most the threshold quantity in the period
+type SlowMover struct {
+	ProductID         int        `json:"product_id"`
+	ProductName       string     `json:"product_name"`
+	CategoryName      string     `json:"category_name"`
+	Stock             int        `json:"stock"`
+	QuantitySold      int        `json:"quantity_sold"`
+	LastSoldAt        *time.Time `json:"last_sold_at"`         // null when never sold
+	DaysSinceLastSale *int       `json:"days_since_last_sale"` // null when never sold
+	StockValue        float64    `json:"stock_value"`          // at cost price, or selling price when there is none
+}
+
+type InventoryValuation struct {
+	AsOf          time.Time                    `json:"as_of"`
+	TotalQuantity int                          `json:"total_quantity"`
+	TotalValue    float64                      `json:"total_value"`
+	Categories    []CategoryInventoryValuation `json:"categories"`
+	Products      []ProductInventoryValuation  `json:"products"`
+}
+
+type CategoryInventoryValuation struct {
+	CategoryID   int     `json:"category_id"`
+	CategoryName string  `json:"category_name"`
+	Quantity     int     `json:"quantity"`
+	Value        float64 `json:"value"`
+}
+
+type ProductInventoryValuation struct {
+	ProductID    int     `json:"product_id"`
+	ProductName  string  `json:"product_name"`
+	CategoryID   int     `json:"category_id"`
+	CategoryName string  `json:"category_name"`
+	Quantity     int     `json:"quantity"`
+	UnitValue    float64 `json:"unit_value"`
+	ValueBasis   string  `json:"value_basis"` // cost_price, or price when the product has no cost price
+	Value        float64 `json:"value"`
+}
+
+// ProductPair is two products bought in the same transaction.
+// Support is the share of transactions containing both, confidence the share of transactions with one
+// product that also contain the other, and lift how much more often they go together than by chance.
+type ProductPair struct {
+	ProductAID     int     `json:"product_a_id"`
+	ProductAName   string  `json:"product_a_name"`
+	ProductBID     int     `json:"product_b_id"`
+	ProductBName   string  `json:"product_b_name"`
+	Transactions   int     `json:"transactions"`
+	Support        float64 `json:"support"`
+	ConfidenceAToB float64 `json:"confidence_a_to_b"`
+	ConfidenceBToA float64 `json:"confidence_b_to_a"`
+	Lift           float64 `json:"lift"`
+}
+
+// BoughtWith is a product that often appears in transactions with another product
+type BoughtWith struct {
+	ProductID    int     `json:"product_id"`
+	ProductName  string  `json:"product_name"`
+	Price        float64 `json:"price"`
+	Stock        int     `json:"stock"`
+	Transactions int     `json:"transactions"`
+	Confidence   float64 `json:"confidence"`
+	Lift         float64 `json:"lift"`
+}
+
+type ABCReport struct {
+	StartDate  string            `json:"start_date"`
+	EndDate    string            `json:"end_date"`
+	Metric     string            `json:"metric"`      // revenue or gross_profit
+	ThresholdA float64           `json:"threshold_a"` // cumulative % covered by class A
+	ThresholdB float64           `json:"threshold_b"` // cumulative % covered by classes A and B
+	Total      float64           `json:"total"`
+	Classes    []ABCClassSummary `json:"classes"`
+	Products   []ABCProduct      `json:"products"`
+}
+
+type ABCClassSummary struct {
+	Class    string  `json:"class"`
+	Products int     `json:"products"`
+	Value    float64 `json:"value"`
+	SharePct float64 `json:"share_pct"`
+}
+
+type ABCProduct struct {
+	Rank          int     `json:"rank"`
+	ProductID     int     `json:"product_id"`
+	ProductName   string  `json:"product_name"`
+	CategoryName  string  `json:"category_name"`
+	Quantity      int     `json:"quantity"`
+	Revenue       float64 `json:"revenue"`
+	GrossProfit   float64 `json:"gross_profit"`
+	SharePct      float64 `json:"share_pct"`
+	CumulativePct float64 `json:"cumulative_pct"`
+	Class         string  `json:"class"`
+}
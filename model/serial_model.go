@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+const (
+	SerialInStock = "in_stock"
+	SerialSold    = "sold"
+	SerialRemoved = "removed"
+)
+
+// SerialLookup tells where a serial number came from and, once sold, the transaction it was sold in
+type SerialLookup struct {
+	ProductID     int          `json:"product_id"`
+	ProductName   string       `json:"product_name"`
+	SerialNumber  string       `json:"serial_number"`
+	Status        string       `json:"status"`
+	ReceivedAt    time.Time    `json:"received_at"`
+	SoldAt        *time.Time   `json:"sold_at,omitempty"`
+	TransactionID int          `json:"transaction_id,omitempty"`
+	Transaction   *Transaction `json:"transaction,omitempty"`
+}
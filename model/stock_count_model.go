@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+const (
+	StockCountOpen      = "open"
+	StockCountApproved  = "approved"
+	StockCountCancelled = "cancelled"
+)
+
+type StockCount struct {
+	ID              int        `json:"id"`
+	Status          string     `json:"status"`
+	Note            string     `json:"note"`
+	CreatedBy       string     `json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	ApprovedBy      string     `json:"approved_by,omitempty"`
+	ApprovedAt      *time.Time `json:"approved_at,omitempty"`
+	TotalProducts   int        `json:"total_products"`
+	CountedProducts int        `json:"counted_products"`
+}
+
+type StockCountInput struct {
+	Note  string `json:"note"`
+	Actor string `json:"actor"`
+}
+
+// StockCountEntryInput is one batch of counted quantities sent by a handheld device.
+// Quantities from several batches for the same product are added together.
+// A batch resent with the same BatchKey is applied only once.
+type StockCountEntryInput struct {
+	BatchKey string                `json:"batch_key"`
+	Device   string                `json:"device"`
+	Actor    string                `json:"actor"`
+	Items    []StockCountEntryItem `json:"items"`
+}
+
+type StockCountEntryItem struct {
+	ProductID int  `json:"product_id"`
+	Quantity  int  `json:"quantity"`
+	Replace   bool `json:"replace"` // sets the counted quantity instead of adding to it, to correct a miscount
+}
+
+type StockCountApprovalInput struct {
+	Actor string `json:"actor"`
+}
+
+type StockCountVarianceReport struct {
+	StockCountID       int                      `json:"stock_count_id"`
+	Status             string                   `json:"status"`
+	CountedProducts    int                      `json:"counted_products"`
+	UncountedProducts  int                      `json:"uncounted_products"`
+	TotalVarianceQty   int                      `json:"total_variance_qty"`
+	TotalVarianceValue float64                  `json:"total_variance_value"`
+	Lines              []StockCountVarianceLine `json:"lines"`
+}
+
+type StockCountVarianceLine struct {
+	ProductID       int     `json:"product_id"`
+	ProductName     string  `json:"product_name"`
+	SystemQuantity  int     `json:"system_quantity"`
+	CountedQuantity *int    `json:"counted_quantity"` // null when not counted yet
+	Variance        int     `json:"variance"`
+	UnitValue       float64 `json:"unit_value"`
+	VarianceValue   float64 `json:"variance_value"`
+}
@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+const (
+	StockMovementSale       = "sale"
+	StockMovementRefund     = "refund"
+	StockMovementAdjustment = "adjustment"
+	StockMovementReceipt    = "receipt"
+	StockMovementTransfer   = "transfer"
+)
+
+type StockMovement struct {
+	ID             int       `json:"id"`
+	ProductID      int       `json:"product_id"`
+	Type           string    `json:"type"`
+	QuantityChange int       `json:"quantity_change"`
+	QuantityBefore int       `json:"quantity_before"`
+	QuantityAfter  int       `json:"quantity_after"`
+	Reason         string    `json:"reason,omitempty"`
+	Actor          string    `json:"actor,omitempty"`
+	Reference      string    `json:"reference,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type StockAdjustmentInput struct {
+	Type           string   `json:"type"` // adjustment (default), transfer or refund
+	QuantityChange int      `json:"quantity_change"`
+	Reason         string   `json:"reason"`
+	Actor          string   `json:"actor"`
+	Reference      string   `json:"reference"`
+	BatchInput              // batch-tracked products only
+	SerialNumbers  []string `json:"serial_numbers"` // serial-tracked products only, one per unit
+	TransactionID  int      `json:"-"`              // refunded transaction, parsed from a TRX-{id} reference
+}
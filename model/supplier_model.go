@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+type Supplier struct {
+	ID      int    `json:"id"`
+	Name    string `json:"name"`
+	Phone   string `json:"phone"`
+	Address string `json:"address"`
+}
+
+// SupplierPurchase is one received line, used to see what was paid to a supplier over time
+type SupplierPurchase struct {
+	GoodsReceiptID  int       `json:"goods_receipt_id"`
+	PurchaseOrderID int       `json:"purchase_order_id"`
+	ReceivedAt      time.Time `json:"received_at"`
+	ProductID       int       `json:"product_id"`
+	ProductName     string    `json:"product_name"`
+	Quantity        int       `json:"quantity"`
+	UnitCost        float64   `json:"unit_cost"`
+	Total           float64   `json:"total"`
+}
@@ -3,35 +3,131 @@ package model
 import "time"
 
 type Transaction struct {
-	ID         int                 `json:"id"`
-	TotalPrice float64             `json:"total_price"`
-	CreatedAt  time.Time           `json:"created_at"`
-	Details    []TransactionDetail `json:"details"`
+	ID            int                 `json:"id"`
+	TotalPrice    float64             `json:"total_price"`
+	CustomerName  string              `json:"customer_name,omitempty"`
+	CustomerPhone string              `json:"customer_phone,omitempty"`
+	CreatedAt     time.Time           `json:"created_at"`
+	Details       []TransactionDetail `json:"details"`
 }
 
 type TransactionDetail struct {
-	ID            int     `json:"id"`
-	TransactionID int     `json:"transaction_id"`
-	ProductID     int     `json:"product_id"`
-	ProductName   string  `json:"product_name,omitempty"`
-	Quantity      int     `json:"quantity"`
-	Subtotal      float64 `json:"subtotal"`
+	ID            int                         `json:"id"`
+	TransactionID int                         `json:"transaction_id"`
+	ProductID     int                         `json:"product_id"`
+	ProductName   string                      `json:"product_name,omitempty"`
+	Quantity      int                         `json:"quantity"`
+	Subtotal      float64                     `json:"subtotal"`
+	UnitCost      float64                     `json:"unit_cost"`
+	Note          string                      `json:"note,omitempty"`
+	Modifiers     []TransactionDetailModifier `json:"modifiers,omitempty"`
+	SerialNumbers []string                    `json:"serial_numbers,omitempty"`
+}
+
+// TransactionDetailModifier is a snapshot of a modifier chosen for a line
+type TransactionDetailModifier struct {
+	ModifierOptionID int     `json:"modifier_option_id"`
+	GroupName        string  `json:"group_name"`
+	Name             string  `json:"name"`
+	PriceDelta       float64 `json:"price_delta"`
 }
 
 type CheckoutItem struct {
-	ProductID int `json:"product_id"`
-	Quantity  int `json:"quantity"`
+	ProductID     int      `json:"product_id"`
+	Quantity      int      `json:"quantity"`
+	ModifierIDs   []int    `json:"modifier_ids"`
+	Note          string   `json:"note"`
+	SerialNumbers []string `json:"serial_numbers"` // required for serial-tracked products, one per unit
 }
 
 type CheckoutRequest struct {
-	Items []CheckoutItem `json:"items"`
+	Items         []CheckoutItem `json:"items"`
+	CustomerName  string         `json:"customer_name"`
+	CustomerPhone string         `json:"customer_phone"`
 }
 
 type TransactionReportRequest struct {
 	TotalRevenue        float64 `json:"total_revenue"`
 	TotalTransactions   int     `json:"total_transaksi"`
+	TotalCOGS           float64 `json:"total_cogs"`
+	GrossProfit         float64 `json:"gross_profit"`
+	GrossMarginPct      float64 `json:"gross_margin_pct"`
 	BestSellingProducts struct {
 		Name     string `json:"nama"`
 		Quantity int    `json:"qty_terjual"`
 	} `json:"produk_terlaris"`
+	Products   []ProductProfit   `json:"products"`
+	Comparison *ReportComparison `json:"comparison,omitempty"`
+}
+
+const (
+	CompareNone     = ""
+	ComparePrevious = "previous"  // the period of the same length just before
+	CompareLastYear = "last_year" // the same dates one year earlier
+)
+
+// ReportMetrics are the headline figures compared between periods
+type ReportMetrics struct {
+	Revenue             float64 `json:"revenue"`
+	Transactions        int     `json:"transactions"`
+	AvgBasket           float64 `json:"avg_basket"`
+	ItemsPerTransaction float64 `json:"items_per_transaction"`
+}
+
+// MetricChange is the difference to the compared period; Pct is null when the compared value is zero
+type MetricChange struct {
+	Absolute float64  `json:"absolute"`
+	Pct      *float64 `json:"pct"`
+}
+
+type ReportComparison struct {
+	Mode              string        `json:"mode"`
+	StartDate         string        `json:"start_date"`
+	EndDate           string        `json:"end_date"`
+	PreviousStartDate string        `json:"previous_start_date"`
+	PreviousEndDate   string        `json:"previous_end_date"`
+	Current           ReportMetrics `json:"current"`
+	Previous          ReportMetrics `json:"previous"`
+	Change            struct {
+		Revenue             MetricChange `json:"revenue"`
+		Transactions        MetricChange `json:"transactions"`
+		AvgBasket           MetricChange `json:"avg_basket"`
+		ItemsPerTransaction MetricChange `json:"items_per_transaction"`
+	} `json:"change"`
+}
+
+type ProductProfit struct {
+	ProductID      int     `json:"product_id"`
+	Name           string  `json:"name"`
+	Quantity       int     `json:"quantity"`
+	Revenue        float64 `json:"revenue"`
+	COGS           float64 `json:"cogs"`
+	GrossProfit    float64 `json:"gross_profit"`
+	GrossMarginPct float64 `json:"gross_margin_pct"`
+}
+
+// TransactionSummary is a transaction without its lines, used for listings
+type TransactionSummary struct {
+	ID            int       `json:"id"`
+	TotalPrice    float64   `json:"total_price"`
+	CustomerName  string    `json:"customer_name,omitempty"`
+	CustomerPhone string    `json:"customer_phone,omitempty"`
+	ItemCount     int       `json:"item_count"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// TransactionLine is a sold line flattened with its transaction, used for line-item exports
+type TransactionLine struct {
+	TransactionID int       `json:"transaction_id"`
+	CreatedAt     time.Time `json:"created_at"`
+	CustomerName  string    `json:"customer_name,omitempty"`
+	DetailID      int       `json:"detail_id"`
+	ProductID     int       `json:"product_id"`
+	ProductName   string    `json:"product_name"`
+	CategoryName  string    `json:"category_name"`
+	Quantity      int       `json:"quantity"`
+	Subtotal      float64   `json:"subtotal"`
+	UnitCost      float64   `json:"unit_cost"`
+	Modifiers     string    `json:"modifiers,omitempty"` // "Group: Option; ..."
+	Note          string    `json:"note,omitempty"`
 }
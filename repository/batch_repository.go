@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"database/sql"
+	"kasir-api/apperror"
+	"kasir-api/model"
+)
+
+type BatchRepository struct {
+	db *sql.DB
+}
+
+func NewBatchRepository(db *sql.DB) *BatchRepository {
+	return &BatchRepository{db: db}
+}
+
+// GetBatchesByProductID returns the batches still holding stock in FEFO order
+func (repo *BatchRepository) GetBatchesByProductID(productID int) ([]model.ProductBatch, error) {
+	var exists bool
+	err := repo.db.QueryRow("SELECT EXISTS (SELECT 1 FROM product WHERE id = $1)", productID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, apperror.NotFound("No product found")
+	}
+
+	rows, err := repo.db.Query(`
+		SELECT b.id, b.product_id, p.name, b.lot_number, b.expiry_date, b.quantity, b.received_at
+		FROM product_batch b
+		JOIN product p ON p.id = b.product_id
+		WHERE b.product_id = $1 AND b.quantity > 0
+		ORDER BY b.expiry_date NULLS LAST, b.id`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batches := make([]model.ProductBatch, 0)
+	for rows.Next() {
+		var b model.ProductBatch
+		if err := scanBatch(rows, &b); err != nil {
+			return nil, err
+		}
+		batches = append(batches, b)
+	}
+	return batches, rows.Err()
+}
+
+func scanBatch(row interface{ Scan(...interface{}) error }, b *model.ProductBatch) error {
+	var expiry sql.NullTime
+	err := row.Scan(&b.ID, &b.ProductID, &b.ProductName, &b.LotNumber, &expiry, &b.Quantity, &b.ReceivedAt)
+	if err != nil {
+		return err
+	}
+	if expiry.Valid {
+		b.ExpiryDate = &expiry.Time
+	}
+	return nil
+}
+
+func isBatchTracked(tx *sql.Tx, productID int) (bool, error) {
+	var tracked bool
+	err := tx.QueryRow("SELECT batch_tracked FROM product WHERE id = $1", productID).Scan(&tracked)
+	if err == sql.ErrNoRows {
+		return false, apperror.NotFound("Product with ID %d not found", productID)
+	}
+	return tracked, err
+}
+
+// reconcileBatches brings the batches of a product whose batch tracking is switched (back) on in line with
+// its stock. Lots left from earlier tracking are kept: stock they do not cover becomes an OPENING batch,
+// and stock sold while tracking was off is taken from them earliest expiry first.
+func reconcileBatches(tx *sql.Tx, productID int, stock int) error {
+	var inBatches int
+	err := tx.QueryRow("SELECT COALESCE(SUM(quantity), 0) FROM product_batch WHERE product_id = $1", productID).Scan(&inBatches)
+	if err != nil {
+		return err
+	}
+	switch {
+	case stock > inBatches:
+		_, err = tx.Exec("INSERT INTO product_batch (product_id, lot_number, quantity) VALUES ($1, 'OPENING', $2)",
+			productID, stock-inBatches)
+	case stock < inBatches:
+		_, err = takeFromBatches(tx, productID, inBatches-stock, "")
+	}
+	return err
+}
+
+// adjustBatches keeps the batches of a batch-tracked product in line with a stock change.
+// A given batch ID is adjusted directly; otherwise incoming stock opens a new batch and outgoing
+// stock is taken earliest-expiry first, expired batches included (they are the first to write off).
+// It returns the ID of the batch that received the stock, if any. Untracked products are left alone.
+func adjustBatches(tx *sql.Tx, productID int, delta int, batch model.BatchInput, goodsReceiptLineID int) (int, error) {
+	tracked, err := isBatchTracked(tx, productID)
+	if err != nil || !tracked || delta == 0 {
+		return 0, err
+	}
+
+	if batch.BatchID != 0 {
+		result, err := tx.Exec("UPDATE product_batch SET quantity = quantity + $1 WHERE id = $2 AND product_id = $3 AND quantity + $1 >= 0",
+			delta, batch.BatchID, productID)
+		if err != nil {
+			return 0, err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		if rows == 0 {
+			return 0, apperror.InsufficientStock("Batch with ID %d not found for product %d or has insufficient quantity", batch.BatchID, productID)
+		}
+		return batch.BatchID, nil
+	}
+
+	if delta > 0 {
+		if batch.LotNumber == "" {
+			return 0, apperror.Validation("Lot number is required for batch-tracked product %d", productID)
+		}
+		var receiptLineID interface{}
+		if goodsReceiptLineID != 0 {
+			receiptLineID = goodsReceiptLineID
+		}
+		var batchID int
+		err := tx.QueryRow(`
+			INSERT INTO product_batch (product_id, lot_number, expiry_date, quantity, goods_receipt_line_id)
+			VALUES ($1, $2, NULLIF($3, '')::date, $4, $5)
+			RETURNING id`, productID, batch.LotNumber, batch.ExpiryDate, delta, receiptLineID).Scan(&batchID)
+		return batchID, err
+	}
+
+	_, err = takeFromBatches(tx, productID, -delta, "")
+	return 0, err
+}
+
+// takeFromBatches deducts quantity from the product's batches, earliest expiry first, and returns how much was
+// taken from each batch. When today (a business date, YYYY-MM-DD) is set, batches that expired before it are
+// skipped; an empty today includes expired batches.
+func takeFromBatches(tx *sql.Tx, productID int, quantity int, today string) (map[int]int, error) {
+	query := `
+		SELECT id, quantity FROM product_batch
+		WHERE product_id = $1 AND quantity > 0`
+	args := []interface{}{productID}
+	if today != "" {
+		query += " AND (expiry_date IS NULL OR expiry_date >= $2::date)"
+		args = append(args, today)
+	}
+	query += " ORDER BY expiry_date NULLS LAST, id FOR UPDATE"
+
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	type batchQty struct{ id, quantity int }
+	available := make([]batchQty, 0)
+	for rows.Next() {
+		var b batchQty
+		if err := rows.Scan(&b.id, &b.quantity); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		available = append(available, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	taken := make(map[int]int)
+	remaining := quantity
+	for _, b := range available {
+		if remaining == 0 {
+			break
+		}
+		take := min(b.quantity, remaining)
+		if _, err := tx.Exec("UPDATE product_batch SET quantity = quantity - $1 WHERE id = $2", take, b.id); err != nil {
+			return nil, err
+		}
+		taken[b.id] = take
+		remaining -= take
+	}
+
+	if remaining > 0 {
+		if today == "" {
+			return nil, apperror.InsufficientStock("Insufficient batch quantity for product %d", productID)
+		}
+		var expired int
+		err := tx.QueryRow("SELECT COALESCE(SUM(quantity), 0) FROM product_batch WHERE product_id = $1 AND expiry_date < $2::date",
+			productID, today).Scan(&expired)
+		if err != nil {
+			return nil, err
+		}
+		if expired == 0 {
+			return nil, apperror.InsufficientStock("Insufficient batch quantity for product %d: its batches hold %d, requested %d",
+				productID, quantity-remaining, quantity)
+		}
+		return nil, apperror.InsufficientStock("Insufficient sellable stock for product %d: %d in expired batches cannot be sold", productID, expired)
+	}
+	return taken, nil
+}
@@ -2,7 +2,7 @@ package repository
 
 import (
 	"database/sql"
-	"errors"
+	"kasir-api/apperror"
 	"kasir-api/model"
 )
 
@@ -14,11 +14,30 @@ func NewCategoryRepository(db *sql.DB) *CategoryRepository {
 	return &CategoryRepository{db: db}
 }
 
-func (repo *CategoryRepository) GetAllCategories() ([]model.Category, error) {
+var categorySortColumns = map[string]string{
+	"id":   "id",
+	"name": "category",
+}
+
+// GetAllCategories returns the page of categories matching filter and the number of matches across all pages
+func (repo *CategoryRepository) GetAllCategories(filter model.CategoryFilter) ([]model.Category, int, error) {
 	query := "SELECT id, category, description FROM category"
-	rows, err := repo.db.Query(query)
+	args := []interface{}{}
+	if filter.Name != "" {
+		query += " WHERE category ILIKE $1"
+		args = append(args, "%"+filter.Name+"%")
+	}
+
+	var total int
+	err := repo.db.QueryRow("SELECT COUNT(*) FROM ("+query+") matches", args...).Scan(&total)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+
+	query += orderAndPage(filter.ListOptions, categorySortColumns, "id", &args)
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
 	}
 	defer rows.Close()
 
@@ -31,11 +50,11 @@ func (repo *CategoryRepository) GetAllCategories() ([]model.Category, error) {
 			&p.Description,
 		)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		category = append(category, p)
 	}
-	return category, nil
+	return category, total, rows.Err()
 }
 
 func (repo *CategoryRepository) Create(category *model.Category) error {
@@ -55,7 +74,7 @@ func (repo *CategoryRepository) GetCategoryByID(id int) (*model.Category, error)
 		&c.Description,
 	)
 	if err == sql.ErrNoRows {
-		return nil, errors.New("No category found")
+		return nil, apperror.NotFound("No category found")
 	}
 	if err != nil {
 		return nil, err
@@ -77,7 +96,7 @@ func (repo *CategoryRepository) Update(category *model.Category) error {
 	}
 
 	if rows == 0 {
-		return errors.New("No category found")
+		return apperror.NotFound("No category found")
 	}
 
 	return nil
@@ -95,7 +114,7 @@ func (repo *CategoryRepository) Delete(id int) error {
 	}
 
 	if rows == 0 {
-		return errors.New("No category found")
+		return apperror.NotFound("No category found")
 	}
 
 	return err
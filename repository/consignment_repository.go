@@ -0,0 +1,243 @@
+package repository
+
+import (
+	"database/sql"
+	"kasir-api/apperror"
+	"kasir-api/model"
+	"time"
+)
+
+type ConsignmentRepository struct {
+	db *sql.DB
+}
+
+func NewConsignmentRepository(db *sql.DB) *ConsignmentRepository {
+	return &ConsignmentRepository{db: db}
+}
+
+// GetConsignmentReport sums the unsettled consigned lines sold in the period per supplier and product.
+// from (inclusive) and to (exclusive) are the UTC bounds of the business days startDate to endDate.
+// supplierID 0 means every supplier.
+func (repo *ConsignmentRepository) GetConsignmentReport(startDate string, endDate string, from time.Time, to time.Time, supplierID int) (*model.ConsignmentReport, error) {
+	rows, err := repo.db.Query(`
+		SELECT
+			s.id, s.name, td.product_id, p.name,
+			SUM(td.quantity), SUM(td.subtotal), SUM(td.consignment_payout * td.quantity)
+		FROM transaction_detail td
+		JOIN transaction t ON t.id = td.transaction_id
+		JOIN supplier s ON s.id = td.consignor_id
+		JOIN product p ON p.id = td.product_id
+		WHERE td.consignment_settlement_id IS NULL
+			AND t.created_at >= $1 AND t.created_at < $2
+			AND ($3 = 0 OR td.consignor_id = $3)
+		GROUP BY s.id, s.name, td.product_id, p.name
+		ORDER BY s.name, s.id, p.name`, from.UTC(), to.UTC(), supplierID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := model.ConsignmentReport{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Suppliers: make([]model.ConsignmentSupplierSummary, 0),
+	}
+	for rows.Next() {
+		var sup model.ConsignmentSupplierSummary
+		var line model.ConsignmentProductLine
+		err := rows.Scan(&sup.SupplierID, &sup.SupplierName, &line.ProductID, &line.ProductName, &line.Quantity, &line.Revenue, &line.Payout)
+		if err != nil {
+			return nil, err
+		}
+
+		n := len(report.Suppliers)
+		if n == 0 || report.Suppliers[n-1].SupplierID != sup.SupplierID {
+			sup.Products = make([]model.ConsignmentProductLine, 0)
+			report.Suppliers = append(report.Suppliers, sup)
+			n++
+		}
+		current := &report.Suppliers[n-1]
+		current.Products = append(current.Products, line)
+		current.TotalQuantity += line.Quantity
+		current.TotalRevenue += line.Revenue
+		current.TotalPayout += line.Payout
+		report.TotalPayout += line.Payout
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+func (repo *ConsignmentRepository) GetAllSettlements(supplierID int, status string) ([]model.ConsignmentSettlement, error) {
+	rows, err := repo.db.Query(settlementSelect+`
+		WHERE ($1 = 0 OR cs.supplier_id = $1) AND ($2 = '' OR cs.status = $2)
+		ORDER BY cs.created_at DESC, cs.id DESC`, supplierID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settlements := make([]model.ConsignmentSettlement, 0)
+	for rows.Next() {
+		var cs model.ConsignmentSettlement
+		if err := scanSettlement(rows, &cs); err != nil {
+			return nil, err
+		}
+		settlements = append(settlements, cs)
+	}
+	return settlements, rows.Err()
+}
+
+// GetSettlementByID returns the settlement with its totals per product
+func (repo *ConsignmentRepository) GetSettlementByID(id int) (*model.ConsignmentSettlement, error) {
+	var cs model.ConsignmentSettlement
+	err := scanSettlement(repo.db.QueryRow(settlementSelect+" WHERE cs.id = $1", id), &cs)
+	if err == sql.ErrNoRows {
+		return nil, apperror.NotFound("No consignment settlement found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.db.Query(`
+		SELECT td.product_id, p.name, SUM(td.quantity), SUM(td.subtotal), SUM(td.consignment_payout * td.quantity)
+		FROM transaction_detail td
+		JOIN product p ON p.id = td.product_id
+		WHERE td.consignment_settlement_id = $1
+		GROUP BY td.product_id, p.name
+		ORDER BY p.name`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	cs.Products = make([]model.ConsignmentProductLine, 0)
+	for rows.Next() {
+		var line model.ConsignmentProductLine
+		if err := rows.Scan(&line.ProductID, &line.ProductName, &line.Quantity, &line.Revenue, &line.Payout); err != nil {
+			return nil, err
+		}
+		cs.Products = append(cs.Products, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &cs, nil
+}
+
+// CreateSettlement claims every unsettled consigned line of the supplier sold in the period, whose business days
+// span from (inclusive) to (exclusive), so the same sale can never be paid out twice
+func (repo *ConsignmentRepository) CreateSettlement(input *model.ConsignmentSettlementInput, from time.Time, to time.Time) (*model.ConsignmentSettlement, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow(`
+		INSERT INTO consignment_settlement (supplier_id, period_start, period_end, status)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id`, input.SupplierID, input.StartDate, input.EndDate, model.ConsignmentSettlementUnpaid).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(`
+		UPDATE transaction_detail td
+		SET consignment_settlement_id = $1
+		FROM transaction t
+		WHERE t.id = td.transaction_id
+			AND td.consignor_id = $2
+			AND td.consignment_settlement_id IS NULL
+			AND t.created_at >= $3 AND t.created_at < $4`, id, input.SupplierID, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rows == 0 {
+		return nil, apperror.Validation("No unsettled consignment sales for this supplier in the period")
+	}
+
+	_, err = tx.Exec(`
+		UPDATE consignment_settlement
+		SET total_quantity = totals.quantity, total_payout = totals.payout
+		FROM (
+			SELECT SUM(quantity) AS quantity, SUM(consignment_payout * quantity) AS payout
+			FROM transaction_detail
+			WHERE consignment_settlement_id = $1
+		) totals
+		WHERE id = $1`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return repo.GetSettlementByID(id)
+}
+
+func (repo *ConsignmentRepository) MarkPaid(id int, input *model.ConsignmentPaymentInput) (*model.ConsignmentSettlement, error) {
+	result, err := repo.db.Exec(`
+		UPDATE consignment_settlement
+		SET status = $1, paid_at = CURRENT_TIMESTAMP, paid_by = $2, payment_reference = $3
+		WHERE id = $4 AND status = $5`,
+		model.ConsignmentSettlementPaid, input.Actor, input.Reference, id, model.ConsignmentSettlementUnpaid)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rows == 0 {
+		cs, err := repo.GetSettlementByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, apperror.Conflict("Consignment settlement is already %s", cs.Status)
+	}
+
+	return repo.GetSettlementByID(id)
+}
+
+const settlementSelect = `
+	SELECT
+		cs.id, cs.supplier_id, s.name, cs.period_start, cs.period_end, cs.total_quantity, cs.total_payout,
+		cs.status, cs.created_at, cs.paid_at, cs.paid_by, cs.payment_reference
+	FROM consignment_settlement cs
+	JOIN supplier s ON s.id = cs.supplier_id`
+
+func scanSettlement(row interface{ Scan(...interface{}) error }, cs *model.ConsignmentSettlement) error {
+	var paidAt sql.NullTime
+	err := row.Scan(
+		&cs.ID,
+		&cs.SupplierID,
+		&cs.SupplierName,
+		&cs.PeriodStart,
+		&cs.PeriodEnd,
+		&cs.TotalQuantity,
+		&cs.TotalPayout,
+		&cs.Status,
+		&cs.CreatedAt,
+		&paidAt,
+		&cs.PaidBy,
+		&cs.PaymentReference,
+	)
+	if err != nil {
+		return err
+	}
+	if paidAt.Valid {
+		cs.PaidAt = &paidAt.Time
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"database/sql"
+	"kasir-api/apperror"
+	"kasir-api/model"
+	"math"
+	"time"
+)
+
+// recordDailySales adds a checked-out transaction to the daily rollups of salesDate
+func recordDailySales(tx *sql.Tx, salesDate string, total float64, details []model.TransactionDetail) error {
+	type productSales struct {
+		quantity int
+		revenue  float64
+		cogs     float64
+	}
+
+	items := 0
+	products := make(map[int]*productSales)
+	order := make([]int, 0)
+	for _, d := range details {
+		items += d.Quantity
+		p, ok := products[d.ProductID]
+		if !ok {
+			p = &productSales{}
+			products[d.ProductID] = p
+			order = append(order, d.ProductID)
+		}
+		p.quantity += d.Quantity
+		p.revenue += d.Subtotal
+		p.cogs += d.UnitCost * float64(d.Quantity)
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO daily_sales (sales_date, transactions, items, revenue)
+		VALUES ($1, 1, $2, $3)
+		ON CONFLICT (sales_date) DO UPDATE SET
+			transactions = daily_sales.transactions + 1,
+			items = daily_sales.items + EXCLUDED.items,
+			revenue = daily_sales.revenue + EXCLUDED.revenue`, salesDate, items, total)
+	if err != nil {
+		return err
+	}
+
+	// A product on several lines of the transaction still counts as one transaction
+	for _, productID := range order {
+		p := products[productID]
+		_, err := tx.Exec(`
+			INSERT INTO daily_product_sales (sales_date, product_id, quantity, revenue, cogs, transactions)
+			VALUES ($1, $2, $3, $4, $5, 1)
+			ON CONFLICT (sales_date, product_id) DO UPDATE SET
+				quantity = daily_product_sales.quantity + EXCLUDED.quantity,
+				revenue = daily_product_sales.revenue + EXCLUDED.revenue,
+				cogs = daily_product_sales.cogs + EXCLUDED.cogs,
+				transactions = daily_product_sales.transactions + 1`,
+			salesDate, productID, p.quantity, p.revenue, p.cogs)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetSalesDateRange returns the first and last day with transactions in tz, or empty strings when there are none
+func (repo *TransactionRepository) GetSalesDateRange(tz string) (string, string, error) {
+	var first, last sql.NullTime
+	err := repo.db.QueryRow(`
+		SELECT
+			MIN((created_at AT TIME ZONE 'UTC' AT TIME ZONE $1)::date),
+			MAX((created_at AT TIME ZONE 'UTC' AT TIME ZONE $1)::date)
+		FROM transaction`, tz).Scan(&first, &last)
+	if err != nil || !first.Valid {
+		return "", "", err
+	}
+	return first.Time.Format("2006-01-02"), last.Time.Format("2006-01-02"), nil
+}
+
+// RebuildDailySales recomputes the rollups of startDate to endDate, days in tz, from the transactions
+// created from (inclusive) to (exclusive), the UTC bounds of those days. It returns the number of days with sales.
+func (repo *TransactionRepository) RebuildDailySales(startDate string, endDate string, tz string, from time.Time, to time.Time) (int, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Checkouts upsert the rollups and another rebuild may run at the same time: hold both tables until the
+	// commit so no sale is counted twice or lost between the delete and the reinsert. SHARE ROW EXCLUSIVE
+	// conflicts with itself and with the ROW EXCLUSIVE lock of an upsert, and still lets reports read.
+	if _, err := tx.Exec("LOCK TABLE daily_sales, daily_product_sales IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM daily_sales WHERE sales_date BETWEEN $1 AND $2", startDate, endDate); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("DELETE FROM daily_product_sales WHERE sales_date BETWEEN $1 AND $2", startDate, endDate); err != nil {
+		return 0, err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO daily_sales (sales_date, transactions, items, revenue)
+		SELECT
+			(t.created_at AT TIME ZONE 'UTC' AT TIME ZONE $1)::date AS sales_date,
+			COUNT(*),
+			COALESCE(SUM(i.items), 0),
+			SUM(t.total_price)
+		FROM transaction t
+		LEFT JOIN (
+			SELECT transaction_id, SUM(quantity) AS items
+			FROM transaction_detail
+			GROUP BY transaction_id
+		) i ON i.transaction_id = t.id
+		WHERE t.created_at >= $2 AND t.created_at < $3
+		GROUP BY sales_date`, tz, from.UTC(), to.UTC())
+	if err != nil {
+		return 0, err
+	}
+	days, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO daily_product_sales (sales_date, product_id, quantity, revenue, cogs, transactions)
+		SELECT
+			(t.created_at AT TIME ZONE 'UTC' AT TIME ZONE $1)::date AS sales_date,
+			td.product_id,
+			SUM(td.quantity),
+			SUM(td.subtotal),
+			SUM(td.unit_cost * td.quantity),
+			COUNT(DISTINCT td.transaction_id)
+		FROM transaction_detail td
+		JOIN transaction t ON t.id = td.transaction_id
+		WHERE t.created_at >= $2 AND t.created_at < $3
+		GROUP BY sales_date, td.product_id`, tz, from.UTC(), to.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int(days), nil
+}
+
+// GetDailySalesReport builds the same report as GetTransactionsByDateRange from the daily rollups
+func (repo *TransactionRepository) GetDailySalesReport(startDate string, endDate string) (*model.TransactionReportRequest, error) {
+	var report model.TransactionReportRequest
+
+	err := repo.db.QueryRow(`
+		SELECT COALESCE(SUM(transactions), 0), COALESCE(SUM(revenue), 0)
+		FROM daily_sales
+		WHERE sales_date BETWEEN $1 AND $2`, startDate, endDate).Scan(
+		&report.TotalTransactions,
+		&report.TotalRevenue,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if report.TotalTransactions == 0 {
+		return nil, apperror.NotFound("Tidak ada transaksi di periode waktu tersebut")
+	}
+
+	err = repo.db.QueryRow(`
+		SELECT p.name, SUM(s.quantity) AS qty_terjual
+		FROM daily_product_sales s
+		JOIN product p ON s.product_id = p.id
+		WHERE s.sales_date BETWEEN $1 AND $2
+		GROUP BY s.product_id, p.name
+		ORDER BY qty_terjual DESC, s.product_id
+		LIMIT 1`, startDate, endDate).Scan(
+		&report.BestSellingProducts.Name,
+		&report.BestSellingProducts.Quantity,
+	)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	rows, err := repo.db.Query(`
+		SELECT s.product_id, p.name, SUM(s.quantity), SUM(s.revenue), SUM(s.cogs)
+		FROM daily_product_sales s
+		JOIN product p ON s.product_id = p.id
+		WHERE s.sales_date BETWEEN $1 AND $2
+		GROUP BY s.product_id, p.name
+		ORDER BY SUM(s.revenue) - SUM(s.cogs) DESC`, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report.Products = make([]model.ProductProfit, 0)
+	for rows.Next() {
+		var p model.ProductProfit
+		err := rows.Scan(&p.ProductID, &p.Name, &p.Quantity, &p.Revenue, &p.COGS)
+		if err != nil {
+			return nil, err
+		}
+		p.GrossProfit = p.Revenue - p.COGS
+		p.GrossMarginPct = marginPct(p.GrossProfit, p.Revenue)
+		report.TotalCOGS += p.COGS
+		report.Products = append(report.Products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report.GrossProfit = report.TotalRevenue - report.TotalCOGS
+	report.GrossMarginPct = marginPct(report.GrossProfit, report.TotalRevenue)
+
+	return &report, nil
+}
+
+// GetDailyReportMetrics is GetReportMetrics read from the daily rollups
+func (repo *TransactionRepository) GetDailyReportMetrics(startDate string, endDate string) (*model.ReportMetrics, error) {
+	var m model.ReportMetrics
+	var items int
+	err := repo.db.QueryRow(`
+		SELECT COALESCE(SUM(transactions), 0), COALESCE(SUM(revenue), 0), COALESCE(SUM(items), 0)
+		FROM daily_sales
+		WHERE sales_date BETWEEN $1 AND $2`, startDate, endDate).Scan(&m.Transactions, &m.Revenue, &items)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Transactions > 0 {
+		m.AvgBasket = math.Round(m.Revenue/float64(m.Transactions)*100) / 100
+		m.ItemsPerTransaction = math.Round(float64(items)/float64(m.Transactions)*100) / 100
+	}
+	return &m, nil
+}
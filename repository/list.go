@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"fmt"
+	"kasir-api/model"
+)
+
+// defaultListLimit is the page size of a list request that does not set a limit
+const defaultListLimit = 50
+
+// orderAndPage returns the ORDER BY, LIMIT and OFFSET clauses of a list query. columns maps the
+// sortable fields to their SQL expressions; the id column breaks ties so pages do not overlap.
+// The limit and offset are appended to args as placeholders; a zero limit means defaultListLimit.
+func orderAndPage(opts model.ListOptions, columns map[string]string, id string, args *[]interface{}) string {
+	column, ok := columns[opts.SortBy]
+	if !ok {
+		column = id
+	}
+	direction := "ASC"
+	if opts.Order == "desc" {
+		direction = "DESC"
+	}
+
+	clause := fmt.Sprintf(" ORDER BY %s %s", column, direction)
+	if column != id {
+		clause += ", " + id + " " + direction
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+	*args = append(*args, limit)
+	clause += fmt.Sprintf(" LIMIT $%d", len(*args))
+	if opts.Offset > 0 {
+		*args = append(*args, opts.Offset)
+		clause += fmt.Sprintf(" OFFSET $%d", len(*args))
+	}
+	return clause
+}
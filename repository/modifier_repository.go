@@ -0,0 +1,385 @@
+package repository
+
+import (
+	"database/sql"
+	"kasir-api/apperror"
+	"kasir-api/model"
+	"slices"
+
+	"github.com/lib/pq"
+)
+
+type ModifierRepository struct {
+	db *sql.DB
+}
+
+func NewModifierRepository(db *sql.DB) *ModifierRepository {
+	return &ModifierRepository{db: db}
+}
+
+// ProductPrices returns the price of each of the product IDs that exist
+func (repo *ModifierRepository) ProductPrices(ids []int) (map[int]float64, error) {
+	rows, err := repo.db.Query("SELECT id, price FROM product WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make(map[int]float64)
+	for rows.Next() {
+		var id int
+		var price float64
+		if err := rows.Scan(&id, &price); err != nil {
+			return nil, err
+		}
+		prices[id] = price
+	}
+	return prices, rows.Err()
+}
+
+func (repo *ModifierRepository) GetAllModifierGroups() ([]model.ModifierGroup, error) {
+	rows, err := repo.db.Query("SELECT id, name, required, min_select, max_select FROM modifier_group ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make([]model.ModifierGroup, 0)
+	for rows.Next() {
+		var g model.ModifierGroup
+		err := rows.Scan(&g.ID, &g.Name, &g.Required, &g.MinSelect, &g.MaxSelect)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range groups {
+		if err := repo.loadGroupRelations(&groups[i]); err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+// GetModifierGroupByID
+func (repo *ModifierRepository) GetModifierGroupByID(id int) (*model.ModifierGroup, error) {
+	var g model.ModifierGroup
+	err := repo.db.QueryRow("SELECT id, name, required, min_select, max_select FROM modifier_group WHERE id = $1", id).Scan(
+		&g.ID,
+		&g.Name,
+		&g.Required,
+		&g.MinSelect,
+		&g.MaxSelect,
+	)
+	if err == sql.ErrNoRows {
+		return nil, apperror.NotFound("No modifier group found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.loadGroupRelations(&g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// GetModifierGroupsByProductID returns the groups attached to a product, used by the POS to build the order screen
+func (repo *ModifierRepository) GetModifierGroupsByProductID(productID int) ([]model.ModifierGroup, error) {
+	rows, err := repo.db.Query(`
+		SELECT g.id, g.name, g.required, g.min_select, g.max_select
+		FROM modifier_group g
+		JOIN product_modifier_group pmg ON pmg.group_id = g.id
+		WHERE pmg.product_id = $1
+		ORDER BY g.id`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make([]model.ModifierGroup, 0)
+	for rows.Next() {
+		var g model.ModifierGroup
+		err := rows.Scan(&g.ID, &g.Name, &g.Required, &g.MinSelect, &g.MaxSelect)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range groups {
+		if err := repo.loadGroupRelations(&groups[i]); err != nil {
+			return nil, err
+		}
+	}
+	return groups, nil
+}
+
+func (repo *ModifierRepository) Create(group *model.ModifierGroup) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow("INSERT INTO modifier_group (name, required, min_select, max_select) VALUES ($1, $2, $3, $4) RETURNING id",
+		group.Name, group.Required, group.MinSelect, group.MaxSelect).Scan(&group.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := saveGroupOptions(tx, group); err != nil {
+		return err
+	}
+	if err := saveGroupProducts(tx, group); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repo *ModifierRepository) Update(group *model.ModifierGroup) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE modifier_group SET name = $1, required = $2, min_select = $3, max_select = $4 WHERE id = $5",
+		group.Name, group.Required, group.MinSelect, group.MaxSelect, group.ID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return apperror.NotFound("No modifier group found")
+	}
+
+	if err := saveGroupOptions(tx, group); err != nil {
+		return err
+	}
+	if err := saveGroupProducts(tx, group); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (repo *ModifierRepository) Delete(id int) error {
+	result, err := repo.db.Exec("DELETE FROM modifier_group WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return apperror.NotFound("No modifier group found")
+	}
+
+	return nil
+}
+
+func (repo *ModifierRepository) loadGroupRelations(g *model.ModifierGroup) error {
+	rows, err := repo.db.Query("SELECT id, group_id, name, price_delta FROM modifier_option WHERE group_id = $1 ORDER BY id", g.ID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	g.Options = make([]model.ModifierOption, 0)
+	for rows.Next() {
+		var o model.ModifierOption
+		if err := rows.Scan(&o.ID, &o.GroupID, &o.Name, &o.PriceDelta); err != nil {
+			return err
+		}
+		g.Options = append(g.Options, o)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	productRows, err := repo.db.Query("SELECT product_id FROM product_modifier_group WHERE group_id = $1 ORDER BY product_id", g.ID)
+	if err != nil {
+		return err
+	}
+	defer productRows.Close()
+
+	g.ProductIDs = make([]int, 0)
+	for productRows.Next() {
+		var productID int
+		if err := productRows.Scan(&productID); err != nil {
+			return err
+		}
+		g.ProductIDs = append(g.ProductIDs, productID)
+	}
+	return productRows.Err()
+}
+
+// saveGroupOptions updates options that carry an ID, inserts the rest and removes the ones no longer listed
+func saveGroupOptions(tx *sql.Tx, g *model.ModifierGroup) error {
+	keep := make([]int, 0, len(g.Options))
+	for i := range g.Options {
+		o := &g.Options[i]
+		o.GroupID = g.ID
+		if o.ID != 0 {
+			result, err := tx.Exec("UPDATE modifier_option SET name = $1, price_delta = $2 WHERE id = $3 AND group_id = $4",
+				o.Name, o.PriceDelta, o.ID, g.ID)
+			if err != nil {
+				return err
+			}
+			rows, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if rows == 0 {
+				return apperror.NotFound("Modifier option with ID %d not found in this group", o.ID)
+			}
+		} else {
+			err := tx.QueryRow("INSERT INTO modifier_option (group_id, name, price_delta) VALUES ($1, $2, $3) RETURNING id",
+				g.ID, o.Name, o.PriceDelta).Scan(&o.ID)
+			if err != nil {
+				return err
+			}
+		}
+		keep = append(keep, o.ID)
+	}
+
+	rows, err := tx.Query("SELECT id FROM modifier_option WHERE group_id = $1", g.ID)
+	if err != nil {
+		return err
+	}
+	stale := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		if !slices.Contains(keep, id) {
+			stale = append(stale, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range stale {
+		if _, err := tx.Exec("DELETE FROM modifier_option WHERE id = $1", id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveGroupProducts replaces the set of products the group is attached to
+func saveGroupProducts(tx *sql.Tx, g *model.ModifierGroup) error {
+	if _, err := tx.Exec("DELETE FROM product_modifier_group WHERE group_id = $1", g.ID); err != nil {
+		return err
+	}
+	for _, productID := range g.ProductIDs {
+		_, err := tx.Exec("INSERT INTO product_modifier_group (product_id, group_id) VALUES ($1, $2) ON CONFLICT DO NOTHING", productID, g.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveModifiers checks the chosen option IDs against the groups attached to the product
+// and returns the snapshot to store with the transaction detail.
+// Groups without options are still loaded so their minimum selection is enforced.
+func resolveModifiers(tx *sql.Tx, productID int, optionIDs []int) ([]model.TransactionDetailModifier, error) {
+	rows, err := tx.Query(`
+		SELECT g.id, g.name, g.required, g.min_select, g.max_select, o.id, o.name, o.price_delta
+		FROM product_modifier_group pmg
+		JOIN modifier_group g ON g.id = pmg.group_id
+		LEFT JOIN modifier_option o ON o.group_id = g.id
+		WHERE pmg.product_id = $1
+		ORDER BY g.id, o.id`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make(map[int]*model.ModifierGroup)
+	groupOrder := make([]int, 0)
+	options := make(map[int]model.ModifierOption)
+	for rows.Next() {
+		var g model.ModifierGroup
+		var optionID sql.NullInt64
+		var optionName sql.NullString
+		var priceDelta sql.NullFloat64
+		err := rows.Scan(&g.ID, &g.Name, &g.Required, &g.MinSelect, &g.MaxSelect, &optionID, &optionName, &priceDelta)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := groups[g.ID]; !ok {
+			groups[g.ID] = &g
+			groupOrder = append(groupOrder, g.ID)
+		}
+		if optionID.Valid {
+			options[int(optionID.Int64)] = model.ModifierOption{
+				ID:         int(optionID.Int64),
+				GroupID:    g.ID,
+				Name:       optionName.String,
+				PriceDelta: priceDelta.Float64,
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	chosen := make([]model.TransactionDetailModifier, 0, len(optionIDs))
+	selected := make(map[int]int)
+	seen := make(map[int]bool)
+	for _, id := range optionIDs {
+		o, ok := options[id]
+		if !ok {
+			return nil, apperror.Validation("Modifier option with ID %d is not available for product %d", id, productID)
+		}
+		if seen[id] {
+			return nil, apperror.Validation("Modifier option with ID %d selected more than once", id)
+		}
+		seen[id] = true
+		selected[o.GroupID]++
+		chosen = append(chosen, model.TransactionDetailModifier{
+			ModifierOptionID: o.ID,
+			GroupName:        groups[o.GroupID].Name,
+			Name:             o.Name,
+			PriceDelta:       o.PriceDelta,
+		})
+	}
+
+	for _, groupID := range groupOrder {
+		g := groups[groupID]
+		minSelect := g.MinSelect
+		if g.Required && minSelect < 1 {
+			minSelect = 1
+		}
+		if selected[groupID] < minSelect {
+			return nil, apperror.Validation("Modifier %s requires at least %d selection(s) for product %d", g.Name, minSelect, productID)
+		}
+		if g.MaxSelect > 0 && selected[groupID] > g.MaxSelect {
+			return nil, apperror.Validation("Modifier %s allows at most %d selection(s) for product %d", g.Name, g.MaxSelect, productID)
+		}
+	}
+
+	return chosen, nil
+}
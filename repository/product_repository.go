@@ -2,8 +2,11 @@ package repository
 
 import (
 	"database/sql"
-	"errors"
+	"fmt"
+	"kasir-api/apperror"
 	"kasir-api/model"
+	"math"
+	"strings"
 )
 
 type ProductRepository struct {
@@ -14,20 +17,117 @@ func NewProductRepository(db *sql.DB) *ProductRepository {
 	return &ProductRepository{db: db}
 }
 
-func (repo *ProductRepository) GetAllProducts(name string) ([]model.Product, error) {
+const productColumns = `
+	p.id, p.name, COALESCE(p.sku, ''), COALESCE(p.barcode, ''), p.price, p.cost_price, p.stock, p.min_stock, p.reorder_qty,
+	p.batch_tracked, p.serial_tracked, p.consignor_id, p.consignment_payout, c.id, c.category, c.description`
+
+const productSelect = `
+	SELECT` + productColumns + `
+	FROM product p
+	JOIN category c ON p.category_id = c.id`
+
+// scanProduct scans the productColumns into p, followed by any extra columns of the query
+func scanProduct(row interface{ Scan(...interface{}) error }, p *model.Product, extra ...interface{}) error {
+	var consignorID sql.NullInt64
+	dest := []interface{}{
+		&p.ID,
+		&p.Name,
+		&p.SKU,
+		&p.Barcode,
+		&p.Price,
+		&p.CostPrice,
+		&p.Stock,
+		&p.MinStock,
+		&p.ReorderQty,
+		&p.BatchTracked,
+		&p.SerialTracked,
+		&consignorID,
+		&p.ConsignmentPayout,
+		&p.Category.ID,
+		&p.Category.Category,
+		&p.Category.Description,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+	if consignorID.Valid {
+		id := int(consignorID.Int64)
+		p.ConsignorID = &id
+	}
+	return nil
+}
+
+var productSortColumns = map[string]string{
+	"id":    "p.id",
+	"name":  "p.name",
+	"price": "p.price",
+	"stock": "p.stock",
+}
+
+// GetAllProducts returns the page of products matching filter and the number of matches across all pages
+func (repo *ProductRepository) GetAllProducts(filter model.ProductFilter) ([]model.Product, int, error) {
+	var conditions []string
 	args := []interface{}{}
-	query := `
-		SELECT 
-			p.id, p.name, p.price, p.stock, c.id, c.category, c.description
-		FROM product p
-		JOIN category c ON p.category_id = c.id`
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Name != "" {
+		where("p.name ILIKE $%d", "%"+filter.Name+"%")
+	}
+	if filter.CategoryID > 0 {
+		where("p.category_id = $%d", filter.CategoryID)
+	}
+	if filter.MinPrice != nil {
+		where("p.price >= $%d", *filter.MinPrice)
+	}
+	if filter.MaxPrice != nil {
+		where("p.price <= $%d", *filter.MaxPrice)
+	}
+	if filter.InStock {
+		conditions = append(conditions, "p.stock > 0")
+	}
+	if filter.LowStock {
+		conditions = append(conditions, "p.min_stock > 0 AND p.stock <= p.min_stock")
+	}
+
+	query := productSelect
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
 
-	if name != "" {
-		query += " WHERE p.name ILIKE $1"
-		args = append(args, "%"+name+"%")
+	var total int
+	err := repo.db.QueryRow("SELECT COUNT(*) FROM ("+query+") matches", args...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
 	}
 
+	query += orderAndPage(filter.ListOptions, productSortColumns, "p.id", &args)
 	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	products := make([]model.Product, 0)
+	for rows.Next() {
+		var p model.Product
+		if err := scanProduct(rows, &p); err != nil {
+			return nil, 0, err
+		}
+		products = append(products, p)
+	}
+	return products, total, rows.Err()
+}
+
+// GetLowStockProducts returns products at or below their minimum stock, emptiest first
+func (repo *ProductRepository) GetLowStockProducts() ([]model.Product, error) {
+	query := productSelect + `
+		WHERE p.min_stock > 0 AND p.stock <= p.min_stock
+		ORDER BY p.stock - p.min_stock, p.name`
+
+	rows, err := repo.db.Query(query)
 	if err != nil {
 		return nil, err
 	}
@@ -36,60 +136,134 @@ func (repo *ProductRepository) GetAllProducts(name string) ([]model.Product, err
 	products := make([]model.Product, 0)
 	for rows.Next() {
 		var p model.Product
-		err := rows.Scan(
-			&p.ID,
-			&p.Name,
-			&p.Price,
-			&p.Stock,
-			&p.Category.ID,
-			&p.Category.Category,
-			&p.Category.Description,
-		)
-		if err != nil {
+		if err := scanProduct(rows, &p); err != nil {
 			return nil, err
 		}
 		products = append(products, p)
 	}
+	return products, rows.Err()
+}
 
-	if len(products) == 0 {
-		return nil, errors.New("No products found")
+// SearchProducts ranks the products matching search by relevance. A product matches when the words of the
+// query prefix-match its name, SKU or barcode, when the query is close to a word run of its name or category
+// despite typos (pg_trgm word similarity), or when it equals the SKU or barcode exactly.
+func (repo *ProductRepository) SearchProducts(search model.ProductSearch) ([]model.ProductSearchResult, error) {
+	args := []interface{}{search.Query, search.TSQuery, search.Limit}
+	boost := "0"
+	sales := ""
+	if search.BoostSales {
+		args = append(args, search.SalesSince)
+		// The daily rollups keep the boost cheap; log(1 + quantity) stops best sellers from burying better matches
+		boost = "0.1 * ln(1 + COALESCE(s.quantity, 0))"
+		sales = `
+		LEFT JOIN (
+			SELECT product_id, SUM(quantity) AS quantity
+			FROM daily_product_sales
+			WHERE sales_date >= $4::date
+			GROUP BY product_id
+		) s ON s.product_id = p.id`
 	}
-	return products, nil
+
+	query := `
+		SELECT` + productColumns + `,
+			CASE WHEN p.barcode = $1 OR lower(p.sku) = lower($1) THEN 10 ELSE 0 END
+				+ ts_rank(p.search_vector, to_tsquery('simple', $2))
+				+ word_similarity($1, p.name)
+				+ 0.5 * word_similarity($1, c.category)
+				+ ` + boost + ` AS score
+		FROM product p
+		JOIN category c ON p.category_id = c.id` + sales + `
+		WHERE p.search_vector @@ to_tsquery('simple', $2)
+			OR $1 <% p.name
+			OR p.category_id IN (SELECT id FROM category WHERE $1 <% category)
+			OR p.barcode = $1
+			OR lower(p.sku) = lower($1)
+		ORDER BY score DESC, p.name
+		LIMIT $3`
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]model.ProductSearchResult, 0)
+	for rows.Next() {
+		var r model.ProductSearchResult
+		if err := scanProduct(rows, &r.Product, &r.Score); err != nil {
+			return nil, err
+		}
+		r.Score = math.Round(r.Score*1000) / 1000
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// CategoryExists reports whether the category exists
+func (repo *ProductRepository) CategoryExists(id int) (bool, error) {
+	var exists bool
+	err := repo.db.QueryRow("SELECT EXISTS (SELECT 1 FROM category WHERE id = $1)", id).Scan(&exists)
+	return exists, err
+}
+
+// SupplierExists reports whether the supplier exists
+func (repo *ProductRepository) SupplierExists(id int) (bool, error) {
+	var exists bool
+	err := repo.db.QueryRow("SELECT EXISTS (SELECT 1 FROM supplier WHERE id = $1)", id).Scan(&exists)
+	return exists, err
 }
 
 func (repo *ProductRepository) Create(input *model.ProductInput) (*model.Product, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Stock starts at zero and the initial quantity goes through the ledger
 	var productID int
-	query := "INSERT INTO product (name, price, stock, category_id) VALUES ($1, $2, $3, $4) RETURNING id"
-	err := repo.db.QueryRow(query, input.Name, input.Price, input.Stock, input.Category_ID).Scan(&productID)
+	query := `
+		INSERT INTO product (name, price, cost_price, stock, category_id, min_stock, reorder_qty, batch_tracked, serial_tracked, consignor_id, consignment_payout, sku, barcode)
+		VALUES ($1, $2, COALESCE($3, 0), 0, $4, $5, $6, COALESCE($7, FALSE), $8, $9, $10, NULLIF($11, ''), NULLIF($12, ''))
+		RETURNING id`
+	err = tx.QueryRow(query, input.Name, input.Price, input.CostPrice, input.Category_ID, input.MinStock, input.ReorderQty,
+		input.BatchTracked, input.SerialTracked, input.ConsignorID, input.ConsignmentPayout, input.SKU, input.Barcode).Scan(&productID)
 	if err != nil {
 		return nil, err
 	}
 
+	if input.Stock != 0 {
+		err = applyStockMovement(tx, &model.StockMovement{
+			ProductID:      productID,
+			Type:           model.StockMovementAdjustment,
+			QuantityChange: input.Stock,
+			Reason:         "Initial stock",
+			Actor:          "system",
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err = adjustBatches(tx, productID, input.Stock, model.BatchInput{LotNumber: "INITIAL"}, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
 	// Fetch the complete product with category information
 	return repo.GetProductByID(productID)
 }
 
 // GetProductByID
 func (repo *ProductRepository) GetProductByID(id int) (*model.Product, error) {
-	query := `
-		SELECT 
-			p.id, p.name, p.price, p.stock, c.id, c.category, c.description
-		FROM product p
-		JOIN category c ON p.category_id = c.id
-		WHERE p.id = $1`
+	query := productSelect + " WHERE p.id = $1"
 
 	var p model.Product
-	err := repo.db.QueryRow(query, id).Scan(
-		&p.ID,
-		&p.Name,
-		&p.Price,
-		&p.Stock,
-		&p.Category.ID,
-		&p.Category.Category,
-		&p.Category.Description,
-	)
+	err := scanProduct(repo.db.QueryRow(query, id), &p)
 	if err == sql.ErrNoRows {
-		return nil, errors.New("No product found")
+		return nil, apperror.NotFound("No product found")
 	}
 	if err != nil {
 		return nil, err
@@ -98,20 +272,56 @@ func (repo *ProductRepository) GetProductByID(id int) (*model.Product, error) {
 	return &p, nil
 }
 
+// Update changes the product details only; stock is changed through stock adjustments.
+// The cost price is kept when the input leaves it out, since goods receipts maintain it.
+// Batch tracking only changes when the input sets it; see reconcileBatches for switching it on.
+// Serial tracking can only be switched on while the product is out of stock.
 func (repo *ProductRepository) Update(id int, input *model.ProductInput) (*model.Product, error) {
-	query := "UPDATE product SET name = $1, price = $2, stock = $3, category_id = $4 WHERE id = $5"
-	result, err := repo.db.Exec(query, input.Name, input.Price, input.Stock, input.Category_ID, id)
+	tx, err := repo.db.Begin()
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	rows, err := result.RowsAffected()
+	var wasTracked, wasSerialTracked bool
+	var stock int
+	err = tx.QueryRow("SELECT batch_tracked, serial_tracked, stock FROM product WHERE id = $1 FOR UPDATE", id).
+		Scan(&wasTracked, &wasSerialTracked, &stock)
+	if err == sql.ErrNoRows {
+		return nil, apperror.NotFound("No product found")
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	if rows == 0 {
-		return nil, errors.New("No product found")
+	// Units already in stock have no serial numbers, so checkout could never sell them
+	if input.SerialTracked && !wasSerialTracked && stock > 0 {
+		return nil, apperror.ValidationFields([]apperror.FieldError{{
+			Field:   "serial_tracked",
+			Message: fmt.Sprintf("cannot be enabled while the product has %d in stock; adjust the stock to zero first and add it back with its serial numbers", stock),
+		}})
+	}
+
+	query := `
+		UPDATE product
+		SET name = $1, price = $2, cost_price = COALESCE($3, cost_price), category_id = $4, min_stock = $5, reorder_qty = $6,
+			batch_tracked = COALESCE($7, batch_tracked), serial_tracked = $8, consignor_id = $9, consignment_payout = $10,
+			sku = NULLIF($11, ''), barcode = NULLIF($12, '')
+		WHERE id = $13`
+	_, err = tx.Exec(query, input.Name, input.Price, input.CostPrice, input.Category_ID, input.MinStock, input.ReorderQty,
+		input.BatchTracked, input.SerialTracked, input.ConsignorID, input.ConsignmentPayout, input.SKU, input.Barcode, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.BatchTracked != nil && *input.BatchTracked && !wasTracked {
+		if err := reconcileBatches(tx, id, stock); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
 	}
 
 	// Fetch the complete updated product with category information
@@ -130,7 +340,7 @@ func (repo *ProductRepository) Delete(id int) error {
 	}
 
 	if rows == 0 {
-		return errors.New("No product found")
+		return apperror.NotFound("No product found")
 	}
 
 	return err
@@ -0,0 +1,333 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"kasir-api/apperror"
+	"kasir-api/model"
+)
+
+type PurchaseOrderRepository struct {
+	db *sql.DB
+}
+
+func NewPurchaseOrderRepository(db *sql.DB) *PurchaseOrderRepository {
+	return &PurchaseOrderRepository{db: db}
+}
+
+func (repo *PurchaseOrderRepository) GetAllPurchaseOrders(status string) ([]model.PurchaseOrder, error) {
+	args := []interface{}{}
+	query := `
+		SELECT po.id, po.supplier_id, s.name, po.status, po.note, po.created_at
+		FROM purchase_order po
+		JOIN supplier s ON s.id = po.supplier_id`
+
+	if status != "" {
+		query += " WHERE po.status = $1"
+		args = append(args, status)
+	}
+	query += " ORDER BY po.created_at DESC, po.id DESC"
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := make([]model.PurchaseOrder, 0)
+	for rows.Next() {
+		var po model.PurchaseOrder
+		err := rows.Scan(&po.ID, &po.SupplierID, &po.SupplierName, &po.Status, &po.Note, &po.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, po)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range orders {
+		if orders[i].Lines, err = repo.getLines(orders[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return orders, nil
+}
+
+// GetPurchaseOrderByID returns the order with its lines and every goods receipt posted against it
+func (repo *PurchaseOrderRepository) GetPurchaseOrderByID(id int) (*model.PurchaseOrder, error) {
+	var po model.PurchaseOrder
+	err := repo.db.QueryRow(`
+		SELECT po.id, po.supplier_id, s.name, po.status, po.note, po.created_at
+		FROM purchase_order po
+		JOIN supplier s ON s.id = po.supplier_id
+		WHERE po.id = $1`, id).Scan(
+		&po.ID,
+		&po.SupplierID,
+		&po.SupplierName,
+		&po.Status,
+		&po.Note,
+		&po.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, apperror.NotFound("No purchase order found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if po.Lines, err = repo.getLines(id); err != nil {
+		return nil, err
+	}
+	if po.Receipts, err = repo.getReceipts(id); err != nil {
+		return nil, err
+	}
+	return &po, nil
+}
+
+func (repo *PurchaseOrderRepository) Create(input *model.PurchaseOrderInput) (*model.PurchaseOrder, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var orderID int
+	err = tx.QueryRow("INSERT INTO purchase_order (supplier_id, status, note) VALUES ($1, $2, $3) RETURNING id",
+		input.SupplierID, model.PurchaseOrderOpen, input.Note).Scan(&orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range input.Lines {
+		_, err = tx.Exec("INSERT INTO purchase_order_line (purchase_order_id, product_id, quantity_ordered, expected_cost) VALUES ($1, $2, $3, $4)",
+			orderID, line.ProductID, line.Quantity, line.ExpectedCost)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return repo.GetPurchaseOrderByID(orderID)
+}
+
+// Cancel closes an order so no further goods can be received against it
+func (repo *PurchaseOrderRepository) Cancel(id int) (*model.PurchaseOrder, error) {
+	result, err := repo.db.Exec("UPDATE purchase_order SET status = $1 WHERE id = $2 AND status IN ($3, $4)",
+		model.PurchaseOrderCancelled, id, model.PurchaseOrderOpen, model.PurchaseOrderPartial)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rows == 0 {
+		po, err := repo.GetPurchaseOrderByID(id)
+		if err != nil {
+			return nil, err
+		}
+		return nil, apperror.Conflict("Purchase order with status %s cannot be cancelled", po.Status)
+	}
+
+	return repo.GetPurchaseOrderByID(id)
+}
+
+// Receive posts a goods receipt against the order: received quantities are added to stock
+// through the ledger and the unit cost is recorded per line. Partial deliveries keep the order open.
+func (repo *PurchaseOrderRepository) Receive(orderID int, input *model.GoodsReceiptInput) (*model.GoodsReceipt, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var status string
+	err = tx.QueryRow("SELECT status FROM purchase_order WHERE id = $1 FOR UPDATE", orderID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return nil, apperror.NotFound("No purchase order found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if status != model.PurchaseOrderOpen && status != model.PurchaseOrderPartial {
+		return nil, apperror.Conflict("Cannot receive goods for a purchase order with status %s", status)
+	}
+
+	receipt := model.GoodsReceipt{
+		PurchaseOrderID: orderID,
+		Note:            input.Note,
+		Actor:           input.Actor,
+		Lines:           make([]model.GoodsReceiptLine, 0, len(input.Lines)),
+	}
+	err = tx.QueryRow("INSERT INTO goods_receipt (purchase_order_id, note, actor) VALUES ($1, $2, $3) RETURNING id, received_at",
+		orderID, input.Note, input.Actor).Scan(&receipt.ID, &receipt.ReceivedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range input.Lines {
+		var productID, ordered, received int
+		var expectedCost float64
+		err := tx.QueryRow(`
+			SELECT product_id, quantity_ordered, quantity_received, expected_cost
+			FROM purchase_order_line
+			WHERE id = $1 AND purchase_order_id = $2
+			FOR UPDATE`, item.PurchaseOrderLineID, orderID).Scan(&productID, &ordered, &received, &expectedCost)
+		if err == sql.ErrNoRows {
+			return nil, apperror.NotFound("Purchase order line with ID %d not found in this order", item.PurchaseOrderLineID)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if item.Quantity <= 0 {
+			return nil, apperror.Validation("Quantity for line %d must be greater than zero", item.PurchaseOrderLineID)
+		}
+		if received+item.Quantity > ordered {
+			return nil, apperror.Validation("Quantity for line %d exceeds the outstanding %d", item.PurchaseOrderLineID, ordered-received)
+		}
+
+		unitCost := item.UnitCost
+		if unitCost == 0 {
+			unitCost = expectedCost
+		}
+
+		_, err = tx.Exec("UPDATE purchase_order_line SET quantity_received = quantity_received + $1 WHERE id = $2",
+			item.Quantity, item.PurchaseOrderLineID)
+		if err != nil {
+			return nil, err
+		}
+
+		line := model.GoodsReceiptLine{
+			GoodsReceiptID:      receipt.ID,
+			PurchaseOrderLineID: item.PurchaseOrderLineID,
+			ProductID:           productID,
+			Quantity:            item.Quantity,
+			UnitCost:            unitCost,
+		}
+		err = tx.QueryRow("INSERT INTO goods_receipt_line (goods_receipt_id, purchase_order_line_id, product_id, quantity, unit_cost) VALUES ($1, $2, $3, $4, $5) RETURNING id",
+			receipt.ID, line.PurchaseOrderLineID, line.ProductID, line.Quantity, line.UnitCost).Scan(&line.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		// Weighted average cost, computed on the stock before this receipt is added
+		_, err = tx.Exec(`
+			UPDATE product
+			SET cost_price = CASE
+				WHEN stock <= 0 THEN $1
+				ELSE (stock * cost_price + $2 * $1) / (stock + $2)
+			END
+			WHERE id = $3`, unitCost, item.Quantity, productID)
+		if err != nil {
+			return nil, err
+		}
+
+		err = applyStockMovement(tx, &model.StockMovement{
+			ProductID:      productID,
+			Type:           model.StockMovementReceipt,
+			QuantityChange: item.Quantity,
+			Actor:          input.Actor,
+			Reference:      fmt.Sprintf("GRN-%d", receipt.ID),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if err = adjustSerials(tx, productID, item.Quantity, item.SerialNumbers, line.ID); err != nil {
+			return nil, err
+		}
+
+		line.BatchID, err = adjustBatches(tx, productID, item.Quantity, model.BatchInput{
+			LotNumber:  item.LotNumber,
+			ExpiryDate: item.ExpiryDate,
+		}, line.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		receipt.Lines = append(receipt.Lines, line)
+	}
+
+	var complete bool
+	err = tx.QueryRow("SELECT BOOL_AND(quantity_received >= quantity_ordered) FROM purchase_order_line WHERE purchase_order_id = $1",
+		orderID).Scan(&complete)
+	if err != nil {
+		return nil, err
+	}
+
+	status = model.PurchaseOrderPartial
+	if complete {
+		status = model.PurchaseOrderReceived
+	}
+	if _, err = tx.Exec("UPDATE purchase_order SET status = $1 WHERE id = $2", status, orderID); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+func (repo *PurchaseOrderRepository) getLines(orderID int) ([]model.PurchaseOrderLine, error) {
+	rows, err := repo.db.Query(`
+		SELECT pol.id, pol.purchase_order_id, pol.product_id, p.name, pol.quantity_ordered, pol.quantity_received, pol.expected_cost
+		FROM purchase_order_line pol
+		JOIN product p ON p.id = pol.product_id
+		WHERE pol.purchase_order_id = $1
+		ORDER BY pol.id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := make([]model.PurchaseOrderLine, 0)
+	for rows.Next() {
+		var l model.PurchaseOrderLine
+		err := rows.Scan(&l.ID, &l.PurchaseOrderID, &l.ProductID, &l.ProductName, &l.QuantityOrdered, &l.QuantityReceived, &l.ExpectedCost)
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+func (repo *PurchaseOrderRepository) getReceipts(orderID int) ([]model.GoodsReceipt, error) {
+	rows, err := repo.db.Query(`
+		SELECT gr.id, gr.note, gr.actor, gr.received_at, grl.id, grl.purchase_order_line_id, grl.product_id, p.name, grl.quantity, grl.unit_cost
+		FROM goods_receipt gr
+		JOIN goods_receipt_line grl ON grl.goods_receipt_id = gr.id
+		JOIN product p ON p.id = grl.product_id
+		WHERE gr.purchase_order_id = $1
+		ORDER BY gr.id, grl.id`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	receipts := make([]model.GoodsReceipt, 0)
+	for rows.Next() {
+		var gr model.GoodsReceipt
+		var l model.GoodsReceiptLine
+		err := rows.Scan(&gr.ID, &gr.Note, &gr.Actor, &gr.ReceivedAt, &l.ID, &l.PurchaseOrderLineID, &l.ProductID, &l.ProductName, &l.Quantity, &l.UnitCost)
+		if err != nil {
+			return nil, err
+		}
+		l.GoodsReceiptID = gr.ID
+		if n := len(receipts); n == 0 || receipts[n-1].ID != gr.ID {
+			gr.PurchaseOrderID = orderID
+			receipts = append(receipts, gr)
+		}
+		receipts[len(receipts)-1].Lines = append(receipts[len(receipts)-1].Lines, l)
+	}
+	return receipts, rows.Err()
+}
@@ -0,0 +1,508 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"kasir-api/apperror"
+	"kasir-api/model"
+	"math"
+	"time"
+)
+
+type ReportRepository struct {
+	db *sql.DB
+}
+
+func NewReportRepository(db *sql.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+// GetSalesVelocity returns every product with its stock settings and the quantity sold in [from, to)
+func (repo *ReportRepository) GetSalesVelocity(from time.Time, to time.Time) ([]model.ReorderSuggestion, error) {
+	rows, err := repo.db.Query(`
+		SELECT
+			p.id, p.name, p.stock, p.min_stock, p.reorder_qty, COALESCE(SUM(td.quantity), 0)
+		FROM product p
+		LEFT JOIN transaction_detail td ON td.product_id = p.id
+			AND td.transaction_id IN (SELECT id FROM transaction WHERE created_at >= $1 AND created_at < $2)
+		GROUP BY p.id, p.name, p.stock, p.min_stock, p.reorder_qty
+		ORDER BY p.id`, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := make([]model.ReorderSuggestion, 0)
+	for rows.Next() {
+		var s model.ReorderSuggestion
+		err := rows.Scan(&s.ProductID, &s.ProductName, &s.Stock, &s.MinStock, &s.ReorderQty, &s.QuantitySold)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, s)
+	}
+	return products, rows.Err()
+}
+
+// GetExpiringBatches returns batches with stock left that expire within the given days of today (a business date),
+// already expired ones included
+func (repo *ReportRepository) GetExpiringBatches(days int, today string) ([]model.ExpiringBatch, error) {
+	rows, err := repo.db.Query(`
+		SELECT
+			b.id, b.product_id, p.name, b.lot_number, b.expiry_date, b.quantity, b.received_at,
+			b.expiry_date - $2::date,
+			b.quantity * CASE WHEN p.cost_price > 0 THEN p.cost_price ELSE p.price END
+		FROM product_batch b
+		JOIN product p ON p.id = b.product_id
+		WHERE b.quantity > 0 AND b.expiry_date IS NOT NULL AND b.expiry_date <= $2::date + $1::int
+		ORDER BY b.expiry_date, p.name`, days, today)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batches := make([]model.ExpiringBatch, 0)
+	for rows.Next() {
+		var b model.ExpiringBatch
+		var expiry sql.NullTime
+		err := rows.Scan(
+			&b.ID,
+			&b.ProductID,
+			&b.ProductName,
+			&b.LotNumber,
+			&expiry,
+			&b.Quantity,
+			&b.ReceivedAt,
+			&b.DaysUntilExpiry,
+			&b.StockValue,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if expiry.Valid {
+			b.ExpiryDate = &expiry.Time
+		}
+		b.Expired = b.DaysUntilExpiry < 0
+		batches = append(batches, b)
+	}
+	return batches, rows.Err()
+}
+
+// lineSalesByProduct sums the transaction lines per product for transactions created from $1 (inclusive) to $2 (exclusive)
+const lineSalesByProduct = `
+	SELECT td.product_id, SUM(td.quantity) AS quantity, SUM(td.subtotal) AS revenue,
+		COUNT(DISTINCT td.transaction_id) AS transactions
+	FROM transaction_detail td
+	JOIN transaction t ON t.id = td.transaction_id
+	WHERE t.created_at >= $1 AND t.created_at < $2
+	GROUP BY td.product_id`
+
+// dailySalesByProduct sums the daily rollups per product for the days $1 to $2
+const dailySalesByProduct = `
+	SELECT product_id, SUM(quantity) AS quantity, SUM(revenue) AS revenue, SUM(transactions) AS transactions
+	FROM daily_product_sales
+	WHERE sales_date BETWEEN $1 AND $2
+	GROUP BY product_id`
+
+// productSalesSort maps the accepted sort fields to their column
+var productSalesSort = map[string]string{
+	"quantity":     "s.quantity",
+	"revenue":      "s.revenue",
+	"transactions": "s.transactions",
+}
+
+// GetProductSales aggregates sales per product ID in the date range and ranks them by the sort field.
+// Ties share a rank and are listed by product ID.
+func (repo *ReportRepository) GetProductSales(filter model.ProductSalesFilter) ([]model.ProductSales, error) {
+	sortExpr, ok := productSalesSort[filter.SortBy]
+	if !ok {
+		return nil, apperror.Validation("Unknown sort field %s", filter.SortBy)
+	}
+
+	source := lineSalesByProduct
+	args := []interface{}{filter.From.UTC(), filter.To.UTC()}
+	if filter.Daily {
+		source = dailySalesByProduct
+		args = []interface{}{filter.StartDate, filter.EndDate}
+	}
+
+	query := `
+		SELECT
+			RANK() OVER (ORDER BY ` + sortExpr + ` DESC),
+			s.product_id, p.name, c.id, c.category,
+			s.quantity, s.revenue, s.transactions
+		FROM (` + source + `) s
+		JOIN product p ON p.id = s.product_id
+		JOIN category c ON c.id = p.category_id`
+
+	if filter.CategoryID != 0 {
+		args = append(args, filter.CategoryID)
+		query += fmt.Sprintf(" WHERE p.category_id = $%d", len(args))
+	}
+
+	query += `
+		ORDER BY ` + sortExpr + ` DESC, s.product_id`
+
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := repo.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sales := make([]model.ProductSales, 0)
+	for rows.Next() {
+		var ps model.ProductSales
+		err := rows.Scan(
+			&ps.Rank,
+			&ps.ProductID,
+			&ps.ProductName,
+			&ps.CategoryID,
+			&ps.CategoryName,
+			&ps.Quantity,
+			&ps.Revenue,
+			&ps.TransactionCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sales = append(sales, ps)
+	}
+	return sales, rows.Err()
+}
+
+// GetSalesTimeSeries returns revenue and transaction count per interval bucket between the dates.
+// Buckets follow the local time of tz and every bucket in the range is returned, with zeros when nothing was sold.
+// created_at is stored in UTC; from and to are the UTC bounds of the date range in tz.
+func (repo *ReportRepository) GetSalesTimeSeries(startDate string, endDate string, interval string, tz string, from time.Time, to time.Time) ([]model.SalesBucket, error) {
+	rows, err := repo.db.Query(`
+		WITH buckets AS (
+			SELECT generate_series(
+				date_trunc($3, $1::date::timestamp),
+				date_trunc($3, $2::date + interval '1 day' - interval '1 second'),
+				('1 ' || $3)::interval
+			) AS bucket
+		)
+		SELECT b.bucket AT TIME ZONE $4, COALESCE(SUM(t.total_price), 0), COUNT(t.id)
+		FROM buckets b
+		LEFT JOIN transaction t ON date_trunc($3, t.created_at AT TIME ZONE 'UTC' AT TIME ZONE $4) = b.bucket
+			AND t.created_at >= $5 AND t.created_at < $6
+		GROUP BY b.bucket
+		ORDER BY b.bucket`, startDate, endDate, interval, tz, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := make([]model.SalesBucket, 0)
+	for rows.Next() {
+		var b model.SalesBucket
+		if err := rows.Scan(&b.Start, &b.Revenue, &b.TransactionCount); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// GetCategorySales returns quantity and revenue per category for the categories sold from (inclusive) to (exclusive)
+func (repo *ReportRepository) GetCategorySales(from time.Time, to time.Time) ([]model.CategorySales, error) {
+	return repo.categorySales(lineSalesByProduct, from.UTC(), to.UTC())
+}
+
+// GetDailyCategorySales is GetCategorySales read from the daily rollups of startDate to endDate
+func (repo *ReportRepository) GetDailyCategorySales(startDate string, endDate string) ([]model.CategorySales, error) {
+	return repo.categorySales(dailySalesByProduct, startDate, endDate)
+}
+
+// categorySales groups the per-product sales of source by category
+func (repo *ReportRepository) categorySales(source string, args ...interface{}) ([]model.CategorySales, error) {
+	rows, err := repo.db.Query(`
+		SELECT c.id, c.category, SUM(s.quantity), SUM(s.revenue)
+		FROM (`+source+`) s
+		JOIN product p ON p.id = s.product_id
+		JOIN category c ON c.id = p.category_id
+		GROUP BY c.id, c.category
+		ORDER BY SUM(s.revenue) DESC, c.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := make([]model.CategorySales, 0)
+	for rows.Next() {
+		var c model.CategorySales
+		if err := rows.Scan(&c.CategoryID, &c.CategoryName, &c.Quantity, &c.Revenue); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// slowMoverSort maps the accepted sort fields to their ORDER BY clause
+var slowMoverSort = map[string]string{
+	"stock_value":          "stock_value DESC",
+	"days_since_last_sale": "last_sold_at ASC NULLS FIRST",
+	"quantity_sold":        "quantity_sold ASC",
+	"stock":                "p.stock DESC",
+}
+
+// GetSlowMovers returns products in stock that sold at most threshold units since the given time
+func (repo *ReportRepository) GetSlowMovers(since time.Time, threshold int, sortBy string) ([]model.SlowMover, error) {
+	order, ok := slowMoverSort[sortBy]
+	if !ok {
+		return nil, apperror.Validation("Unknown sort field %s", sortBy)
+	}
+
+	rows, err := repo.db.Query(`
+		SELECT
+			p.id, p.name, c.category, p.stock,
+			COALESCE(recent.quantity, 0) AS quantity_sold,
+			last_sale.created_at AS last_sold_at,
+			p.stock * CASE WHEN p.cost_price > 0 THEN p.cost_price ELSE p.price END AS stock_value
+		FROM product p
+		JOIN category c ON c.id = p.category_id
+		LEFT JOIN (
+			SELECT td.product_id, SUM(td.quantity) AS quantity
+			FROM transaction_detail td
+			JOIN transaction t ON t.id = td.transaction_id
+			WHERE t.created_at >= $1
+			GROUP BY td.product_id
+		) recent ON recent.product_id = p.id
+		LEFT JOIN (
+			SELECT td.product_id, MAX(t.created_at) AS created_at
+			FROM transaction_detail td
+			JOIN transaction t ON t.id = td.transaction_id
+			GROUP BY td.product_id
+		) last_sale ON last_sale.product_id = p.id
+		WHERE p.stock > 0 AND COALESCE(recent.quantity, 0) <= $2
+		ORDER BY `+order+`, p.id`, since.UTC(), threshold)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := make([]model.SlowMover, 0)
+	for rows.Next() {
+		var m model.SlowMover
+		var lastSold sql.NullTime
+		err := rows.Scan(&m.ProductID, &m.ProductName, &m.CategoryName, &m.Stock, &m.QuantitySold, &lastSold, &m.StockValue)
+		if err != nil {
+			return nil, err
+		}
+		if lastSold.Valid {
+			m.LastSoldAt = &lastSold.Time
+		}
+		products = append(products, m)
+	}
+	return products, rows.Err()
+}
+
+// GetInventoryValuation returns the stock of every owned product as it was at asOf, reconstructed by
+// reversing the stock movements recorded from then on, valued at cost price or selling price when there is none.
+// Consigned products belong to their supplier and are left out.
+func (repo *ReportRepository) GetInventoryValuation(asOf time.Time) ([]model.ProductInventoryValuation, error) {
+	rows, err := repo.db.Query(`
+		SELECT
+			p.id, p.name, c.id, c.category,
+			p.stock - COALESCE(later.quantity_change, 0) AS quantity,
+			p.cost_price, p.price
+		FROM product p
+		JOIN category c ON c.id = p.category_id
+		LEFT JOIN (
+			SELECT product_id, SUM(quantity_change) AS quantity_change
+			FROM stock_movement
+			WHERE created_at >= $1
+			GROUP BY product_id
+		) later ON later.product_id = p.id
+		WHERE p.consignor_id IS NULL AND p.stock - COALESCE(later.quantity_change, 0) <> 0
+		ORDER BY c.category, p.name, p.id`, asOf.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := make([]model.ProductInventoryValuation, 0)
+	for rows.Next() {
+		var v model.ProductInventoryValuation
+		var costPrice, price float64
+		err := rows.Scan(&v.ProductID, &v.ProductName, &v.CategoryID, &v.CategoryName, &v.Quantity, &costPrice, &price)
+		if err != nil {
+			return nil, err
+		}
+		v.UnitValue, v.ValueBasis = costPrice, "cost_price"
+		if costPrice <= 0 {
+			v.UnitValue, v.ValueBasis = price, "price"
+		}
+		v.Value = v.UnitValue * float64(v.Quantity)
+		products = append(products, v)
+	}
+	return products, rows.Err()
+}
+
+// GetLedgerStart returns when the stock ledger received its first movement, the opening balances of
+// migration 002 for products that existed before it. ok is false while the ledger is empty.
+func (repo *ReportRepository) GetLedgerStart() (start time.Time, ok bool, err error) {
+	var first sql.NullTime
+	if err := repo.db.QueryRow("SELECT MIN(created_at) FROM stock_movement").Scan(&first); err != nil {
+		return time.Time{}, false, err
+	}
+	return first.Time, first.Valid, nil
+}
+
+// basketsCTE lists each product once per transaction created from $1 (inclusive) to $2 (exclusive),
+// with the number of such transactions and of transactions per product
+const basketsCTE = `
+	WITH baskets AS (
+		SELECT DISTINCT td.transaction_id, td.product_id
+		FROM transaction_detail td
+		JOIN transaction t ON t.id = td.transaction_id
+		WHERE t.created_at >= $1 AND t.created_at < $2
+	),
+	total AS (
+		SELECT COUNT(DISTINCT transaction_id) AS transactions FROM baskets
+	),
+	item AS (
+		SELECT product_id, COUNT(*) AS transactions FROM baskets GROUP BY product_id
+	)`
+
+// GetProductPairs returns the product pairs bought together in at least minCount transactions,
+// most frequent first
+func (repo *ReportRepository) GetProductPairs(from time.Time, to time.Time, minCount int, limit int) ([]model.ProductPair, error) {
+	rows, err := repo.db.Query(basketsCTE+`,
+		pair AS (
+			SELECT a.product_id AS a_id, b.product_id AS b_id, COUNT(*) AS transactions
+			FROM baskets a
+			JOIN baskets b ON b.transaction_id = a.transaction_id AND b.product_id > a.product_id
+			GROUP BY a.product_id, b.product_id
+			HAVING COUNT(*) >= $3
+		)
+		SELECT pair.a_id, pa.name, pair.b_id, pb.name, pair.transactions, ia.transactions, ib.transactions, total.transactions
+		FROM pair
+		JOIN item ia ON ia.product_id = pair.a_id
+		JOIN item ib ON ib.product_id = pair.b_id
+		JOIN product pa ON pa.id = pair.a_id
+		JOIN product pb ON pb.id = pair.b_id
+		CROSS JOIN total
+		ORDER BY pair.transactions DESC, pair.a_id, pair.b_id
+		LIMIT $4`, from.UTC(), to.UTC(), minCount, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pairs := make([]model.ProductPair, 0)
+	for rows.Next() {
+		var p model.ProductPair
+		var countA, countB, total int
+		err := rows.Scan(&p.ProductAID, &p.ProductAName, &p.ProductBID, &p.ProductBName, &p.Transactions, &countA, &countB, &total)
+		if err != nil {
+			return nil, err
+		}
+		p.Support = ratio(p.Transactions, total)
+		p.ConfidenceAToB = ratio(p.Transactions, countA)
+		p.ConfidenceBToA = ratio(p.Transactions, countB)
+		p.Lift = ratio(p.Transactions*total, countA*countB)
+		pairs = append(pairs, p)
+	}
+	return pairs, rows.Err()
+}
+
+// GetBoughtWith returns the products found in at least minCount of the transactions containing productID,
+// by confidence then lift
+func (repo *ReportRepository) GetBoughtWith(productID int, from time.Time, to time.Time, minCount int, limit int) ([]model.BoughtWith, error) {
+	var exists bool
+	err := repo.db.QueryRow("SELECT EXISTS (SELECT 1 FROM product WHERE id = $1)", productID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, apperror.NotFound("No product found")
+	}
+
+	rows, err := repo.db.Query(basketsCTE+`,
+		together AS (
+			SELECT b.product_id, COUNT(*) AS transactions
+			FROM baskets a
+			JOIN baskets b ON b.transaction_id = a.transaction_id AND b.product_id <> a.product_id
+			WHERE a.product_id = $3
+			GROUP BY b.product_id
+			HAVING COUNT(*) >= $4
+		)
+		SELECT p.id, p.name, p.price, p.stock, together.transactions, ia.transactions, ib.transactions, total.transactions
+		FROM together
+		JOIN item ia ON ia.product_id = $3
+		JOIN item ib ON ib.product_id = together.product_id
+		JOIN product p ON p.id = together.product_id
+		CROSS JOIN total
+		ORDER BY together.transactions::float / ia.transactions DESC,
+			together.transactions::float / ib.transactions DESC, p.id
+		LIMIT $5`, from.UTC(), to.UTC(), productID, minCount, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := make([]model.BoughtWith, 0)
+	for rows.Next() {
+		var b model.BoughtWith
+		var countProduct, countOther, total int
+		err := rows.Scan(&b.ProductID, &b.ProductName, &b.Price, &b.Stock, &b.Transactions, &countProduct, &countOther, &total)
+		if err != nil {
+			return nil, err
+		}
+		b.Confidence = ratio(b.Transactions, countProduct)
+		b.Lift = ratio(b.Transactions*total, countProduct*countOther)
+		products = append(products, b)
+	}
+	return products, rows.Err()
+}
+
+// ratio returns a / b rounded to four decimals
+func ratio(a int, b int) float64 {
+	if b == 0 {
+		return 0
+	}
+	return math.Round(float64(a)/float64(b)*10000) / 10000
+}
+
+// GetProductContribution returns every product with its quantity, revenue and gross profit sold
+// from (inclusive) to (exclusive), including products without sales
+func (repo *ReportRepository) GetProductContribution(from time.Time, to time.Time) ([]model.ABCProduct, error) {
+	rows, err := repo.db.Query(`
+		SELECT
+			p.id, p.name, c.category,
+			COALESCE(SUM(sold.quantity), 0),
+			COALESCE(SUM(sold.subtotal), 0),
+			COALESCE(SUM(sold.subtotal - sold.unit_cost * sold.quantity), 0)
+		FROM product p
+		JOIN category c ON c.id = p.category_id
+		LEFT JOIN (
+			SELECT td.product_id, td.quantity, td.subtotal, td.unit_cost
+			FROM transaction_detail td
+			JOIN transaction t ON t.id = td.transaction_id
+			WHERE t.created_at >= $1 AND t.created_at < $2
+		) sold ON sold.product_id = p.id
+		GROUP BY p.id, p.name, c.category
+		ORDER BY p.id`, from.UTC(), to.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := make([]model.ABCProduct, 0)
+	for rows.Next() {
+		var p model.ABCProduct
+		err := rows.Scan(&p.ProductID, &p.ProductName, &p.CategoryName, &p.Quantity, &p.Revenue, &p.GrossProfit)
+		if err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+	return products, rows.Err()
+}
@@ -0,0 +1,206 @@
+package repository
+
+import (
+	"database/sql"
+	"kasir-api/apperror"
+	"kasir-api/model"
+
+	"github.com/lib/pq"
+)
+
+type SerialRepository struct {
+	db *sql.DB
+}
+
+func NewSerialRepository(db *sql.DB) *SerialRepository {
+	return &SerialRepository{db: db}
+}
+
+// LookupSerial finds a serial number across products; the same number may exist for different products.
+// The sale shown is the latest one, which is kept after a refund.
+func (repo *SerialRepository) LookupSerial(serialNumber string) ([]model.SerialLookup, error) {
+	rows, err := repo.db.Query(`
+		SELECT s.product_id, p.name, s.serial_number, s.status, s.received_at, sale.sold_at, COALESCE(sale.transaction_id, 0)
+		FROM product_serial s
+		JOIN product p ON p.id = s.product_id
+		LEFT JOIN LATERAL (
+			SELECT tds.sold_at, td.transaction_id
+			FROM transaction_detail_serial tds
+			JOIN transaction_detail td ON td.id = tds.transaction_detail_id
+			WHERE tds.serial_id = s.id
+			ORDER BY tds.sold_at DESC, td.transaction_id DESC
+			LIMIT 1
+		) sale ON TRUE
+		WHERE s.serial_number = $1
+		ORDER BY s.id`, serialNumber)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]model.SerialLookup, 0)
+	for rows.Next() {
+		var l model.SerialLookup
+		var soldAt sql.NullTime
+		err := rows.Scan(&l.ProductID, &l.ProductName, &l.SerialNumber, &l.Status, &l.ReceivedAt, &soldAt, &l.TransactionID)
+		if err != nil {
+			return nil, err
+		}
+		if soldAt.Valid {
+			l.SoldAt = &soldAt.Time
+		}
+		results = append(results, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, apperror.NotFound("No serial number found")
+	}
+	return results, nil
+}
+
+func isSerialTracked(tx *sql.Tx, productID int) (bool, error) {
+	var tracked bool
+	err := tx.QueryRow("SELECT serial_tracked FROM product WHERE id = $1", productID).Scan(&tracked)
+	if err == sql.ErrNoRows {
+		return false, apperror.NotFound("Product with ID %d not found", productID)
+	}
+	return tracked, err
+}
+
+// checkSerialCount makes sure a serial-tracked product gets exactly one distinct serial per unit,
+// and that serials are not sent for untracked products
+func checkSerialCount(tracked bool, productID int, quantity int, serials []string) error {
+	if !tracked {
+		if len(serials) > 0 {
+			return apperror.Validation("Product %d is not serial-tracked", productID)
+		}
+		return nil
+	}
+	if len(serials) != quantity {
+		return apperror.Validation("Product %d requires %d serial number(s), got %d", productID, quantity, len(serials))
+	}
+	seen := make(map[string]bool)
+	for _, serial := range serials {
+		if serial == "" {
+			return apperror.Validation("Serial numbers for product %d must not be empty", productID)
+		}
+		if seen[serial] {
+			return apperror.Validation("Serial number %s is listed more than once", serial)
+		}
+		seen[serial] = true
+	}
+	return nil
+}
+
+// adjustSerials registers incoming serials (delta > 0) or removes outgoing ones (delta < 0) for a stock change
+func adjustSerials(tx *sql.Tx, productID int, delta int, serials []string, goodsReceiptLineID int) error {
+	tracked, err := isSerialTracked(tx, productID)
+	if err != nil {
+		return err
+	}
+	quantity := delta
+	if quantity < 0 {
+		quantity = -quantity
+	}
+	if err := checkSerialCount(tracked, productID, quantity, serials); err != nil || !tracked {
+		return err
+	}
+
+	var receiptLineID interface{}
+	if goodsReceiptLineID != 0 {
+		receiptLineID = goodsReceiptLineID
+	}
+
+	for _, serial := range serials {
+		var result sql.Result
+		if delta > 0 {
+			// A serial that was removed earlier may come back into stock
+			result, err = tx.Exec(`
+				INSERT INTO product_serial (product_id, serial_number, status, goods_receipt_line_id)
+				VALUES ($1, $2, $3, $4)
+				ON CONFLICT (product_id, serial_number) DO UPDATE
+				SET status = EXCLUDED.status, goods_receipt_line_id = EXCLUDED.goods_receipt_line_id,
+					received_at = CURRENT_TIMESTAMP
+				WHERE product_serial.status = $5`,
+				productID, serial, model.SerialInStock, receiptLineID, model.SerialRemoved)
+		} else {
+			result, err = tx.Exec("UPDATE product_serial SET status = $1 WHERE product_id = $2 AND serial_number = $3 AND status = $4",
+				model.SerialRemoved, productID, serial, model.SerialInStock)
+		}
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 && delta > 0 {
+			return apperror.Conflict("Serial number %s is already registered for product %d", serial, productID)
+		}
+		if rows == 0 {
+			return apperror.InsufficientStock("Serial number %s is not in stock for product %d", serial, productID)
+		}
+	}
+	return nil
+}
+
+// returnSerials puts sold serials back in stock when the customer returns them. Each serial must have been
+// sold last on one of the given transaction lines. Their sale stays in transaction_detail_serial, so the
+// receipt and warranty history still show it.
+func returnSerials(tx *sql.Tx, productID int, quantity int, serials []string, detailIDs []int64) error {
+	tracked, err := isSerialTracked(tx, productID)
+	if err != nil {
+		return err
+	}
+	if err := checkSerialCount(tracked, productID, quantity, serials); err != nil || !tracked {
+		return err
+	}
+
+	for _, serial := range serials {
+		result, err := tx.Exec(`
+			UPDATE product_serial s
+			SET status = $1
+			WHERE s.product_id = $2 AND s.serial_number = $3 AND s.status = $4
+				AND (SELECT tds.transaction_detail_id FROM transaction_detail_serial tds
+					WHERE tds.serial_id = s.id ORDER BY tds.sold_at DESC LIMIT 1) = ANY($5)`,
+			model.SerialInStock, productID, serial, model.SerialSold, pq.Array(detailIDs))
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return apperror.Conflict("Serial number %s of product %d is not sold in the refunded transaction", serial, productID)
+		}
+	}
+	return nil
+}
+
+// sellSerials marks the serials as sold and records them on the given transaction line
+func sellSerials(tx *sql.Tx, productID int, serials []string, transactionDetailID int) error {
+	for _, serial := range serials {
+		var serialID int
+		err := tx.QueryRow(`
+			UPDATE product_serial SET status = $1
+			WHERE product_id = $2 AND serial_number = $3 AND status = $4
+			RETURNING id`,
+			model.SerialSold, productID, serial, model.SerialInStock).Scan(&serialID)
+		if err == sql.ErrNoRows {
+			return apperror.InsufficientStock("Serial number %s is not in stock for product %d", serial, productID)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("INSERT INTO transaction_detail_serial (transaction_detail_id, serial_id) VALUES ($1, $2)",
+			transactionDetailID, serialID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
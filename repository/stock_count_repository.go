@@ -0,0 +1,341 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"kasir-api/apperror"
+	"kasir-api/model"
+)
+
+type StockCountRepository struct {
+	db *sql.DB
+}
+
+func NewStockCountRepository(db *sql.DB) *StockCountRepository {
+	return &StockCountRepository{db: db}
+}
+
+const stockCountSelect = `
+	SELECT
+		sc.id, sc.status, sc.note, sc.created_by, sc.created_at, sc.approved_by, sc.approved_at,
+		(SELECT COUNT(*) FROM stock_count_line l WHERE l.stock_count_id = sc.id),
+		(SELECT COUNT(*) FROM stock_count_line l WHERE l.stock_count_id = sc.id AND l.counted_quantity IS NOT NULL)
+	FROM stock_count sc`
+
+func scanStockCount(row interface{ Scan(...interface{}) error }, sc *model.StockCount) error {
+	var approvedAt sql.NullTime
+	err := row.Scan(
+		&sc.ID,
+		&sc.Status,
+		&sc.Note,
+		&sc.CreatedBy,
+		&sc.CreatedAt,
+		&sc.ApprovedBy,
+		&approvedAt,
+		&sc.TotalProducts,
+		&sc.CountedProducts,
+	)
+	if err != nil {
+		return err
+	}
+	if approvedAt.Valid {
+		sc.ApprovedAt = &approvedAt.Time
+	}
+	return nil
+}
+
+func (repo *StockCountRepository) GetAllStockCounts() ([]model.StockCount, error) {
+	rows, err := repo.db.Query(stockCountSelect + " ORDER BY sc.created_at DESC, sc.id DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make([]model.StockCount, 0)
+	for rows.Next() {
+		var sc model.StockCount
+		if err := scanStockCount(rows, &sc); err != nil {
+			return nil, err
+		}
+		counts = append(counts, sc)
+	}
+	return counts, rows.Err()
+}
+
+// GetStockCountByID
+func (repo *StockCountRepository) GetStockCountByID(id int) (*model.StockCount, error) {
+	var sc model.StockCount
+	err := scanStockCount(repo.db.QueryRow(stockCountSelect+" WHERE sc.id = $1", id), &sc)
+	if err == sql.ErrNoRows {
+		return nil, apperror.NotFound("No stock count found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &sc, nil
+}
+
+// Create starts a count session and snapshots the current stock of every product,
+// valued at cost price (selling price when no cost is known)
+func (repo *StockCountRepository) Create(input *model.StockCountInput) (*model.StockCount, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRow("INSERT INTO stock_count (status, note, created_by) VALUES ($1, $2, $3) RETURNING id",
+		model.StockCountOpen, input.Note, input.Actor).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO stock_count_line (stock_count_id, product_id, system_quantity, unit_value)
+		SELECT $1, p.id, p.stock, CASE WHEN p.cost_price > 0 THEN p.cost_price ELSE p.price END
+		FROM product p`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return repo.GetStockCountByID(id)
+}
+
+// AddEntries records a batch of counted quantities; quantities for the same product accumulate across batches
+// unless an item replaces the count. A batch whose key was already applied leaves the count unchanged.
+func (repo *StockCountRepository) AddEntries(id int, input *model.StockCountEntryInput) (*model.StockCount, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenStockCount(tx, id); err != nil {
+		return nil, err
+	}
+
+	if input.BatchKey != "" {
+		result, err := tx.Exec(`
+			INSERT INTO stock_count_batch (stock_count_id, batch_key, device) VALUES ($1, $2, $3)
+			ON CONFLICT (stock_count_id, batch_key) DO NOTHING`, id, input.BatchKey, input.Device)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if rows == 0 {
+			return repo.GetStockCountByID(id)
+		}
+	}
+
+	for _, item := range input.Items {
+		result, err := tx.Exec(`
+			UPDATE stock_count_line
+			SET counted_quantity = CASE WHEN $4::boolean THEN $1::int ELSE COALESCE(counted_quantity, 0) + $1::int END
+			WHERE stock_count_id = $2 AND product_id = $3`, item.Quantity, id, item.ProductID, item.Replace)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if rows == 0 {
+			return nil, apperror.Validation("Product with ID %d is not part of this stock count", item.ProductID)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO stock_count_entry (stock_count_id, product_id, quantity, device, counted_by, replaced)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			id, item.ProductID, item.Quantity, input.Device, input.Actor, item.Replace)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return repo.GetStockCountByID(id)
+}
+
+// GetVarianceReport compares counted against snapshotted system quantities, largest value difference first
+func (repo *StockCountRepository) GetVarianceReport(id int) (*model.StockCountVarianceReport, error) {
+	sc, err := repo.GetStockCountByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.db.Query(`
+		SELECT l.product_id, p.name, l.system_quantity, l.counted_quantity, l.unit_value
+		FROM stock_count_line l
+		JOIN product p ON p.id = l.product_id
+		WHERE l.stock_count_id = $1
+		ORDER BY ABS(COALESCE(l.counted_quantity - l.system_quantity, 0) * l.unit_value) DESC, p.name`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := model.StockCountVarianceReport{
+		StockCountID: sc.ID,
+		Status:       sc.Status,
+		Lines:        make([]model.StockCountVarianceLine, 0),
+	}
+	for rows.Next() {
+		var line model.StockCountVarianceLine
+		var counted sql.NullInt64
+		err := rows.Scan(&line.ProductID, &line.ProductName, &line.SystemQuantity, &counted, &line.UnitValue)
+		if err != nil {
+			return nil, err
+		}
+
+		if counted.Valid {
+			c := int(counted.Int64)
+			line.CountedQuantity = &c
+			line.Variance = c - line.SystemQuantity
+			line.VarianceValue = float64(line.Variance) * line.UnitValue
+			report.CountedProducts++
+			report.TotalVarianceQty += line.Variance
+			report.TotalVarianceValue += line.VarianceValue
+		} else {
+			report.UncountedProducts++
+		}
+		report.Lines = append(report.Lines, line)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// Approve posts an adjustment for every counted product whose quantity differs from the snapshot.
+// The variance is applied on top of the current stock so sales made during the count are kept.
+// For batch-tracked products a surplus opens a batch named after the count and a shortage is
+// taken earliest-expiry first. A count does not say which units are missing, so a variance on a
+// serial-tracked product is refused; its serials are corrected with stock adjustments.
+// Products that were not counted are left unchanged. Every product that cannot be adjusted is
+// listed in one validation error and nothing is applied.
+func (repo *StockCountRepository) Approve(id int, input *model.StockCountApprovalInput) (*model.StockCount, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenStockCount(tx, id); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT l.product_id, l.counted_quantity - l.system_quantity, p.serial_tracked
+		FROM stock_count_line l
+		JOIN product p ON p.id = l.product_id
+		WHERE l.stock_count_id = $1 AND l.counted_quantity IS NOT NULL AND l.counted_quantity <> l.system_quantity
+		ORDER BY l.product_id`, id)
+	if err != nil {
+		return nil, err
+	}
+	variances := make([]model.StockMovement, 0)
+	fields := make([]apperror.FieldError, 0)
+	for rows.Next() {
+		var m model.StockMovement
+		var serialTracked bool
+		if err := rows.Scan(&m.ProductID, &m.QuantityChange, &serialTracked); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if serialTracked {
+			fields = append(fields, apperror.FieldError{
+				Field:   "product_id",
+				Message: fmt.Sprintf("product %d is serial-tracked; correct its serial numbers with a stock adjustment and recount it", m.ProductID),
+			})
+			continue
+		}
+		variances = append(variances, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// A shortage that no longer fits the stock or the batches is collected instead of aborting at the first
+	// product; the transaction is rolled back when any is found, so the partial writes are discarded
+	for i := range variances {
+		m := &variances[i]
+		m.Type = model.StockMovementAdjustment
+		m.Reason = fmt.Sprintf("Stock opname #%d", id)
+		m.Actor = input.Actor
+		m.Reference = fmt.Sprintf("SO-%d", id)
+		err := applyStockMovement(tx, m)
+		if err == nil {
+			_, err = adjustBatches(tx, m.ProductID, m.QuantityChange, model.BatchInput{LotNumber: m.Reference}, 0)
+		}
+		var appErr *apperror.Error
+		if errors.As(err, &appErr) && appErr.Code == apperror.CodeInsufficientStock {
+			fields = append(fields, apperror.FieldError{Field: "product_id", Message: appErr.Message})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if len(fields) > 0 {
+		return nil, apperror.ValidationFields(fields)
+	}
+
+	_, err = tx.Exec("UPDATE stock_count SET status = $1, approved_by = $2, approved_at = CURRENT_TIMESTAMP WHERE id = $3",
+		model.StockCountApproved, input.Actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return repo.GetStockCountByID(id)
+}
+
+func (repo *StockCountRepository) Cancel(id int) (*model.StockCount, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockOpenStockCount(tx, id); err != nil {
+		return nil, err
+	}
+
+	if _, err = tx.Exec("UPDATE stock_count SET status = $1 WHERE id = $2", model.StockCountCancelled, id); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return repo.GetStockCountByID(id)
+}
+
+func lockOpenStockCount(tx *sql.Tx, id int) error {
+	var status string
+	err := tx.QueryRow("SELECT status FROM stock_count WHERE id = $1 FOR UPDATE", id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return apperror.NotFound("No stock count found")
+	}
+	if err != nil {
+		return err
+	}
+	if status != model.StockCountOpen {
+		return apperror.Conflict("Stock count is already %s", status)
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"database/sql"
+	"kasir-api/apperror"
+	"kasir-api/model"
+
+	"github.com/lib/pq"
+)
+
+type StockRepository struct {
+	db *sql.DB
+}
+
+func NewStockRepository(db *sql.DB) *StockRepository {
+	return &StockRepository{db: db}
+}
+
+// GetStockHistory returns the ledger of a product, newest first
+func (repo *StockRepository) GetStockHistory(productID int) ([]model.StockMovement, error) {
+	var exists bool
+	err := repo.db.QueryRow("SELECT EXISTS (SELECT 1 FROM product WHERE id = $1)", productID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, apperror.NotFound("No product found")
+	}
+
+	rows, err := repo.db.Query(`
+		SELECT id, product_id, movement_type, quantity_change, quantity_before, quantity_after, reason, actor, reference, created_at
+		FROM stock_movement
+		WHERE product_id = $1
+		ORDER BY created_at DESC, id DESC`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movements := make([]model.StockMovement, 0)
+	for rows.Next() {
+		var m model.StockMovement
+		err := rows.Scan(
+			&m.ID,
+			&m.ProductID,
+			&m.Type,
+			&m.QuantityChange,
+			&m.QuantityBefore,
+			&m.QuantityAfter,
+			&m.Reason,
+			&m.Actor,
+			&m.Reference,
+			&m.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		movements = append(movements, m)
+	}
+	return movements, rows.Err()
+}
+
+func (repo *StockRepository) Adjust(productID int, input *model.StockAdjustmentInput) (*model.StockMovement, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	movement := model.StockMovement{
+		ProductID:      productID,
+		Type:           input.Type,
+		QuantityChange: input.QuantityChange,
+		Reason:         input.Reason,
+		Actor:          input.Actor,
+		Reference:      input.Reference,
+	}
+	if err := applyStockMovement(tx, &movement); err != nil {
+		return nil, err
+	}
+	if input.Type == model.StockMovementRefund {
+		err = refundSale(tx, productID, input)
+	} else {
+		_, err = adjustBatches(tx, productID, input.QuantityChange, input.BatchInput, 0)
+		if err == nil {
+			err = adjustSerials(tx, productID, input.QuantityChange, input.SerialNumbers, 0)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &movement, nil
+}
+
+// refundSale checks a refund against the lines of the refunded transaction and puts the returned units
+// back where the sale took them from: the sold serials, and the batches recorded for the lines.
+// Units sold before the product was batch-tracked go into the batch given in the input.
+func refundSale(tx *sql.Tx, productID int, input *model.StockAdjustmentInput) error {
+	var exists bool
+	err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM transaction WHERE id = $1)", input.TransactionID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return apperror.NotFound("Transaction with ID %d not found", input.TransactionID)
+	}
+
+	// Locking the lines makes concurrent refunds of the same sale wait for each other
+	rows, err := tx.Query("SELECT id, quantity FROM transaction_detail WHERE transaction_id = $1 AND product_id = $2 FOR UPDATE",
+		input.TransactionID, productID)
+	if err != nil {
+		return err
+	}
+	detailIDs := make([]int64, 0)
+	sold := 0
+	for rows.Next() {
+		var id int64
+		var quantity int
+		if err := rows.Scan(&id, &quantity); err != nil {
+			rows.Close()
+			return err
+		}
+		detailIDs = append(detailIDs, id)
+		sold += quantity
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(detailIDs) == 0 {
+		return apperror.Validation("Transaction %d has no line for product %d", input.TransactionID, productID)
+	}
+
+	// The movement of this refund is already recorded, so it is part of the sum
+	var refunded int
+	err = tx.QueryRow(`
+		SELECT COALESCE(SUM(quantity_change), 0) FROM stock_movement
+		WHERE product_id = $1 AND movement_type = $2 AND reference = $3`,
+		productID, model.StockMovementRefund, input.Reference).Scan(&refunded)
+	if err != nil {
+		return err
+	}
+	if refunded > sold {
+		return apperror.Validation("Refund of %d exceeds what is left to refund of product %d in transaction %d: sold %d, refunded %d",
+			input.QuantityChange, productID, input.TransactionID, sold, refunded-input.QuantityChange)
+	}
+
+	if err := returnSerials(tx, productID, input.QuantityChange, input.SerialNumbers, detailIDs); err != nil {
+		return err
+	}
+
+	batchRows, err := tx.Query(`
+		SELECT tdb.transaction_detail_id, tdb.batch_id, tdb.quantity - tdb.refunded_quantity
+		FROM transaction_detail_batch tdb
+		JOIN product_batch b ON b.id = tdb.batch_id
+		WHERE tdb.transaction_detail_id = ANY($1) AND tdb.quantity > tdb.refunded_quantity
+		ORDER BY b.expiry_date DESC NULLS FIRST, b.id DESC
+		FOR UPDATE OF tdb`, pq.Array(detailIDs))
+	if err != nil {
+		return err
+	}
+	type soldBatch struct{ detailID, batchID, quantity int }
+	batches := make([]soldBatch, 0)
+	for batchRows.Next() {
+		var b soldBatch
+		if err := batchRows.Scan(&b.detailID, &b.batchID, &b.quantity); err != nil {
+			batchRows.Close()
+			return err
+		}
+		batches = append(batches, b)
+	}
+	batchRows.Close()
+	if err := batchRows.Err(); err != nil {
+		return err
+	}
+
+	// Latest expiry first, so returned goods go back to the batch that stays sellable longest
+	remaining := input.QuantityChange
+	for _, b := range batches {
+		if remaining == 0 {
+			break
+		}
+		put := min(b.quantity, remaining)
+		if _, err := tx.Exec("UPDATE product_batch SET quantity = quantity + $1 WHERE id = $2", put, b.batchID); err != nil {
+			return err
+		}
+		_, err := tx.Exec("UPDATE transaction_detail_batch SET refunded_quantity = refunded_quantity + $1 WHERE transaction_detail_id = $2 AND batch_id = $3",
+			put, b.detailID, b.batchID)
+		if err != nil {
+			return err
+		}
+		remaining -= put
+	}
+	if remaining > 0 {
+		_, err = adjustBatches(tx, productID, remaining, input.BatchInput, 0)
+		return err
+	}
+	return nil
+}
+
+// applyStockMovement changes the product stock by m.QuantityChange and appends the movement to the ledger.
+// Stock is never allowed to go below zero.
+func applyStockMovement(tx *sql.Tx, m *model.StockMovement) error {
+	err := tx.QueryRow("UPDATE product SET stock = stock + $1 WHERE id = $2 AND stock + $1 >= 0 RETURNING stock",
+		m.QuantityChange, m.ProductID).Scan(&m.QuantityAfter)
+	if err == sql.ErrNoRows {
+		var stock int
+		err = tx.QueryRow("SELECT stock FROM product WHERE id = $1", m.ProductID).Scan(&stock)
+		if err == sql.ErrNoRows {
+			return apperror.NotFound("Product with ID %d not found", m.ProductID)
+		}
+		if err != nil {
+			return err
+		}
+		return apperror.InsufficientStock("Insufficient stock for product %d: available %d, requested %d", m.ProductID, stock, -m.QuantityChange)
+	}
+	if err != nil {
+		return err
+	}
+	m.QuantityBefore = m.QuantityAfter - m.QuantityChange
+
+	return tx.QueryRow(`
+		INSERT INTO stock_movement (product_id, movement_type, quantity_change, quantity_before, quantity_after, reason, actor, reference)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at`,
+		m.ProductID, m.Type, m.QuantityChange, m.QuantityBefore, m.QuantityAfter, m.Reason, m.Actor, m.Reference,
+	).Scan(&m.ID, &m.CreatedAt)
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"database/sql"
+	"kasir-api/apperror"
+	"kasir-api/model"
+)
+
+type SupplierRepository struct {
+	db *sql.DB
+}
+
+func NewSupplierRepository(db *sql.DB) *SupplierRepository {
+	return &SupplierRepository{db: db}
+}
+
+func (repo *SupplierRepository) GetAllSuppliers() ([]model.Supplier, error) {
+	rows, err := repo.db.Query("SELECT id, name, phone, address FROM supplier ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppliers := make([]model.Supplier, 0)
+	for rows.Next() {
+		var s model.Supplier
+		if err := rows.Scan(&s.ID, &s.Name, &s.Phone, &s.Address); err != nil {
+			return nil, err
+		}
+		suppliers = append(suppliers, s)
+	}
+	return suppliers, rows.Err()
+}
+
+func (repo *SupplierRepository) Create(supplier *model.Supplier) error {
+	query := "INSERT INTO supplier (name, phone, address) VALUES ($1, $2, $3) RETURNING id"
+	return repo.db.QueryRow(query, supplier.Name, supplier.Phone, supplier.Address).Scan(&supplier.ID)
+}
+
+// GetSupplierByID
+func (repo *SupplierRepository) GetSupplierByID(id int) (*model.Supplier, error) {
+	var s model.Supplier
+	err := repo.db.QueryRow("SELECT id, name, phone, address FROM supplier WHERE id = $1", id).Scan(
+		&s.ID,
+		&s.Name,
+		&s.Phone,
+		&s.Address,
+	)
+	if err == sql.ErrNoRows {
+		return nil, apperror.NotFound("No supplier found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (repo *SupplierRepository) Update(supplier *model.Supplier) error {
+	query := "UPDATE supplier SET name = $1, phone = $2, address = $3 WHERE id = $4"
+	result, err := repo.db.Exec(query, supplier.Name, supplier.Phone, supplier.Address, supplier.ID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return apperror.NotFound("No supplier found")
+	}
+
+	return nil
+}
+
+func (repo *SupplierRepository) Delete(id int) error {
+	result, err := repo.db.Exec("DELETE FROM supplier WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return apperror.NotFound("No supplier found")
+	}
+
+	return nil
+}
+
+// GetPurchaseHistory lists every received line from the supplier with the unit cost paid, newest first
+func (repo *SupplierRepository) GetPurchaseHistory(supplierID int) ([]model.SupplierPurchase, error) {
+	if _, err := repo.GetSupplierByID(supplierID); err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.db.Query(`
+		SELECT gr.id, gr.purchase_order_id, gr.received_at, grl.product_id, p.name, grl.quantity, grl.unit_cost
+		FROM goods_receipt_line grl
+		JOIN goods_receipt gr ON gr.id = grl.goods_receipt_id
+		JOIN purchase_order po ON po.id = gr.purchase_order_id
+		JOIN product p ON p.id = grl.product_id
+		WHERE po.supplier_id = $1
+		ORDER BY gr.received_at DESC, grl.id`, supplierID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	purchases := make([]model.SupplierPurchase, 0)
+	for rows.Next() {
+		var p model.SupplierPurchase
+		err := rows.Scan(
+			&p.GoodsReceiptID,
+			&p.PurchaseOrderID,
+			&p.ReceivedAt,
+			&p.ProductID,
+			&p.ProductName,
+			&p.Quantity,
+			&p.UnitCost,
+		)
+		if err != nil {
+			return nil, err
+		}
+		p.Total = p.UnitCost * float64(p.Quantity)
+		purchases = append(purchases, p)
+	}
+	return purchases, rows.Err()
+}
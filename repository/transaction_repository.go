@@ -2,9 +2,13 @@ package repository
 
 import (
 	"database/sql"
-	"errors"
 	"fmt"
+	"kasir-api/apperror"
 	"kasir-api/model"
+	"math"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 type TransactionRepository struct {
@@ -15,7 +19,32 @@ func NewTransactionRepository(db *sql.DB) *TransactionRepository {
 	return &TransactionRepository{db: db}
 }
 
-func (repo *TransactionRepository) Checkout(items []model.CheckoutItem) (*model.Transaction, error) {
+type consignment struct {
+	consignorID sql.NullInt64
+	payout      float64
+}
+
+// ExistingProductIDs returns which of the product IDs exist
+func (repo *TransactionRepository) ExistingProductIDs(ids []int) (map[int]bool, error) {
+	rows, err := repo.db.Query("SELECT id FROM product WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		existing[id] = true
+	}
+	return existing, rows.Err()
+}
+
+// Checkout records the sale and adds it to the daily rollups of its day in location, the business timezone
+func (repo *TransactionRepository) Checkout(request *model.CheckoutRequest, location *time.Location) (*model.Transaction, error) {
 	tx, err := repo.db.Begin()
 	if err != nil {
 		return nil, err
@@ -24,109 +53,369 @@ func (repo *TransactionRepository) Checkout(items []model.CheckoutItem) (*model.
 
 	totalPrice := 0.0                             // initiate subtotal -> total all transaction
 	details := make([]model.TransactionDetail, 0) // initiate details model -> later insert to db
+	batchTracked := make([]bool, 0)               // per detail, deduct from batches FEFO
+	consignments := make([]consignment, 0)        // per detail, consignor payout snapshot
 	// loop items
-	for _, item := range items {
+	for _, item := range request.Items {
+		if item.Quantity <= 0 {
+			return nil, apperror.Validation("Quantity for product %d must be greater than zero", item.ProductID)
+		}
+
 		var productPrice float64
-		var stock int
+		var costPrice float64
 		var productName string
+		var tracked bool
+		var serialTracked bool
+		var consignorID sql.NullInt64
+		var consignmentPayout float64
 
-		err := tx.QueryRow("SELECT name, price, stock FROM product WHERE id=$1", item.ProductID).Scan(&productName, &productPrice, &stock)
+		err := tx.QueryRow("SELECT name, price, cost_price, batch_tracked, serial_tracked, consignor_id, consignment_payout FROM product WHERE id=$1", item.ProductID).Scan(
+			&productName, &productPrice, &costPrice, &tracked, &serialTracked, &consignorID, &consignmentPayout)
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("Product with ID %d not found", item.ProductID)
+			return nil, apperror.NotFound("Product with ID %d not found", item.ProductID)
 		}
 		if err != nil {
 			return nil, err
 		}
 
-		subtotal := productPrice * float64(item.Quantity)
-		totalPrice += subtotal
+		if err := checkSerialCount(serialTracked, item.ProductID, item.Quantity, item.SerialNumbers); err != nil {
+			return nil, err
+		}
 
-		_, err = tx.Exec("UPDATE product SET stock = stock - $1 WHERE id = $2 AND stock >= $1", item.Quantity, item.ProductID)
+		modifiers, err := resolveModifiers(tx, item.ProductID, item.ModifierIDs)
 		if err != nil {
 			return nil, err
 		}
 
+		unitPrice := productPrice
+		for _, m := range modifiers {
+			unitPrice += m.PriceDelta
+		}
+		// Several discounting options together, or a later price cut, can still go below zero
+		if unitPrice < 0 {
+			return nil, apperror.Validation("Modifiers take the price of product %d below zero", item.ProductID)
+		}
+
+		subtotal := unitPrice * float64(item.Quantity)
+		totalPrice += subtotal
+
+		// What a consigned product costs us is the payout owed to the consignor
+		if consignorID.Valid {
+			costPrice = consignmentPayout
+		}
+
 		details = append(details, model.TransactionDetail{
-			ProductID:   item.ProductID,
-			ProductName: productName,
-			Quantity:    item.Quantity,
-			Subtotal:    subtotal,
+			ProductID:     item.ProductID,
+			ProductName:   productName,
+			Quantity:      item.Quantity,
+			Subtotal:      subtotal,
+			UnitCost:      costPrice,
+			Note:          item.Note,
+			Modifiers:     modifiers,
+			SerialNumbers: item.SerialNumbers,
 		})
+		batchTracked = append(batchTracked, tracked)
+		consignments = append(consignments, consignment{consignorID, consignmentPayout})
 	}
 
 	var transactionID int
-	err = tx.QueryRow("INSERT INTO transaction (total_price) VALUES ($1) RETURNING id", totalPrice).Scan(&transactionID)
+	var createdAt time.Time
+	err = tx.QueryRow("INSERT INTO transaction (total_price, customer_name, customer_phone) VALUES ($1, $2, $3) RETURNING id, created_at",
+		totalPrice, request.CustomerName, request.CustomerPhone).Scan(&transactionID, &createdAt)
 	if err != nil {
 		return nil, err
 	}
+	// The business day of the sale decides which batches have expired and which rollup it joins
+	salesDate := createdAt.In(location).Format("2006-01-02")
 
 	for i := range details {
-		_, err = tx.Exec("INSERT INTO transaction_detail (transaction_id, product_id, quantity, subtotal) VALUES ($1, $2, $3, $4)",
-			transactionID, details[i].ProductID, details[i].Quantity, details[i].Subtotal)
+		details[i].TransactionID = transactionID
+		err = tx.QueryRow(`
+			INSERT INTO transaction_detail (transaction_id, product_id, quantity, subtotal, unit_cost, note, consignor_id, consignment_payout)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING id`,
+			transactionID, details[i].ProductID, details[i].Quantity, details[i].Subtotal, details[i].UnitCost, details[i].Note,
+			consignments[i].consignorID, consignments[i].payout).Scan(&details[i].ID)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, m := range details[i].Modifiers {
+			_, err = tx.Exec("INSERT INTO transaction_detail_modifier (transaction_detail_id, modifier_option_id, group_name, option_name, price_delta) VALUES ($1, $2, $3, $4, $5)",
+				details[i].ID, m.ModifierOptionID, m.GroupName, m.Name, m.PriceDelta)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if err := sellSerials(tx, details[i].ProductID, details[i].SerialNumbers, details[i].ID); err != nil {
+			return nil, err
+		}
+
+		if batchTracked[i] {
+			taken, err := takeFromBatches(tx, details[i].ProductID, details[i].Quantity, salesDate)
+			if err != nil {
+				return nil, err
+			}
+			for batchID, quantity := range taken {
+				_, err = tx.Exec("INSERT INTO transaction_detail_batch (transaction_detail_id, batch_id, quantity) VALUES ($1, $2, $3)",
+					details[i].ID, batchID, quantity)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		err = applyStockMovement(tx, &model.StockMovement{
+			ProductID:      details[i].ProductID,
+			Type:           model.StockMovementSale,
+			QuantityChange: -details[i].Quantity,
+			Actor:          "system",
+			Reference:      fmt.Sprintf("TRX-%d", transactionID),
+		})
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	if err = recordDailySales(tx, salesDate, totalPrice, details); err != nil {
+		return nil, err
+	}
+
 	if err = tx.Commit(); err != nil {
 		return nil, err
 	}
 
 	return &model.Transaction{
-		ID:         transactionID,
-		TotalPrice: totalPrice,
-		Details:    details,
+		ID:            transactionID,
+		TotalPrice:    totalPrice,
+		CustomerName:  request.CustomerName,
+		CustomerPhone: request.CustomerPhone,
+		CreatedAt:     createdAt,
+		Details:       details,
 	}, nil
 }
 
-func (repo *TransactionRepository) GetTodayTransactions() (*model.TransactionReportRequest, error) {
-	var report model.TransactionReportRequest
-	err := repo.db.QueryRow(`
-			SELECT
-				COUNT(*) AS total_transaksi,
-				COALESCE(SUM(total_price), 0) AS total_revenue
-			FROM transaction
-			WHERE created_at::date = CURRENT_DATE`).Scan(
-		&report.TotalTransactions,
-		&report.TotalRevenue,
+// GetTransactionByID returns a transaction with its lines and chosen modifiers, used for receipts and kitchen tickets
+func (repo *TransactionRepository) GetTransactionByID(id int) (*model.Transaction, error) {
+	var t model.Transaction
+	err := repo.db.QueryRow("SELECT id, total_price, customer_name, customer_phone, created_at FROM transaction WHERE id = $1", id).Scan(
+		&t.ID,
+		&t.TotalPrice,
+		&t.CustomerName,
+		&t.CustomerPhone,
+		&t.CreatedAt,
 	)
+	if err == sql.ErrNoRows {
+		return nil, apperror.NotFound("No transaction found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := repo.db.Query(`
+		SELECT td.id, td.transaction_id, td.product_id, p.name, td.quantity, td.subtotal, td.unit_cost, td.note
+		FROM transaction_detail td
+		JOIN product p ON td.product_id = p.id
+		WHERE td.transaction_id = $1
+		ORDER BY td.id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	t.Details = make([]model.TransactionDetail, 0)
+	detailIndex := make(map[int]int)
+	for rows.Next() {
+		var d model.TransactionDetail
+		err := rows.Scan(&d.ID, &d.TransactionID, &d.ProductID, &d.ProductName, &d.Quantity, &d.Subtotal, &d.UnitCost, &d.Note)
+		if err != nil {
+			return nil, err
+		}
+		detailIndex[d.ID] = len(t.Details)
+		t.Details = append(t.Details, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	modifierRows, err := repo.db.Query(`
+		SELECT tdm.transaction_detail_id, COALESCE(tdm.modifier_option_id, 0), tdm.group_name, tdm.option_name, tdm.price_delta
+		FROM transaction_detail_modifier tdm
+		JOIN transaction_detail td ON td.id = tdm.transaction_detail_id
+		WHERE td.transaction_id = $1
+		ORDER BY tdm.id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer modifierRows.Close()
+
+	for modifierRows.Next() {
+		var detailID int
+		var m model.TransactionDetailModifier
+		err := modifierRows.Scan(&detailID, &m.ModifierOptionID, &m.GroupName, &m.Name, &m.PriceDelta)
+		if err != nil {
+			return nil, err
+		}
+		i := detailIndex[detailID]
+		t.Details[i].Modifiers = append(t.Details[i].Modifiers, m)
+	}
+	if err := modifierRows.Err(); err != nil {
+		return nil, err
+	}
+
+	serialRows, err := repo.db.Query(`
+		SELECT tds.transaction_detail_id, s.serial_number
+		FROM transaction_detail_serial tds
+		JOIN product_serial s ON s.id = tds.serial_id
+		JOIN transaction_detail td ON td.id = tds.transaction_detail_id
+		WHERE td.transaction_id = $1
+		ORDER BY s.id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer serialRows.Close()
+
+	for serialRows.Next() {
+		var detailID int
+		var serial string
+		if err := serialRows.Scan(&detailID, &serial); err != nil {
+			return nil, err
+		}
+		i := detailIndex[detailID]
+		t.Details[i].SerialNumbers = append(t.Details[i].SerialNumbers, serial)
+	}
+	if err := serialRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// EachTransaction calls fn for every transaction created from (inclusive) to (exclusive), oldest first.
+// Rows are read as fn consumes them so long ranges are not held in memory.
+func (repo *TransactionRepository) EachTransaction(from time.Time, to time.Time, fn func(model.TransactionSummary) error) error {
+	rows, err := repo.db.Query(`
+		SELECT t.id, t.total_price, t.customer_name, t.customer_phone,
+			COALESCE((SELECT SUM(td.quantity) FROM transaction_detail td WHERE td.transaction_id = t.id), 0),
+			t.created_at
+		FROM transaction t
+		WHERE t.created_at >= $1 AND t.created_at < $2
+		ORDER BY t.created_at, t.id`, from.UTC(), to.UTC())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t model.TransactionSummary
+		err := rows.Scan(&t.ID, &t.TotalPrice, &t.CustomerName, &t.CustomerPhone, &t.ItemCount, &t.CreatedAt)
+		if err != nil {
+			return err
+		}
+		if err := fn(t); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// EachTransactionLine calls fn for every transaction line sold from (inclusive) to (exclusive), oldest first,
+// reading rows as fn consumes them
+func (repo *TransactionRepository) EachTransactionLine(from time.Time, to time.Time, fn func(model.TransactionLine) error) error {
+	rows, err := repo.db.Query(`
+		SELECT t.id, t.created_at, t.customer_name, td.id, td.product_id, p.name, c.category,
+			td.quantity, td.subtotal, td.unit_cost,
+			COALESCE((
+				SELECT string_agg(tdm.group_name || ': ' || tdm.option_name, '; ' ORDER BY tdm.id)
+				FROM transaction_detail_modifier tdm
+				WHERE tdm.transaction_detail_id = td.id
+			), ''),
+			td.note
+		FROM transaction_detail td
+		JOIN transaction t ON t.id = td.transaction_id
+		JOIN product p ON p.id = td.product_id
+		JOIN category c ON c.id = p.category_id
+		WHERE t.created_at >= $1 AND t.created_at < $2
+		ORDER BY t.created_at, t.id, td.id`, from.UTC(), to.UTC())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var l model.TransactionLine
+		err := rows.Scan(&l.TransactionID, &l.CreatedAt, &l.CustomerName, &l.DetailID, &l.ProductID, &l.ProductName, &l.CategoryName,
+			&l.Quantity, &l.Subtotal, &l.UnitCost, &l.Modifiers, &l.Note)
+		if err != nil {
+			return err
+		}
+		if err := fn(l); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetTodayTransactions reports on transactions created from (inclusive) to (exclusive), the bounds of the business day
+func (repo *TransactionRepository) GetTodayTransactions(from time.Time, to time.Time) (*model.TransactionReportRequest, error) {
+	report, err := repo.getReport("t.created_at >= $1 AND t.created_at < $2", from.UTC(), to.UTC())
 	if err != nil {
 		return nil, err
 	}
 
 	if report.TotalTransactions == 0 {
-		return nil, errors.New("Tidak ada transaksi hari ini")
+		return nil, apperror.NotFound("Tidak ada transaksi hari ini")
 	}
+	return report, nil
+}
 
-	err = repo.db.QueryRow(`
-			SELECT 
-				p.name, SUM(td.quantity) AS qty_terjual
-			FROM transaction_detail td
-			JOIN product p ON td.product_id = p.id
-			JOIN transaction t ON td.transaction_id = t.id
-			WHERE t.created_at::date = CURRENT_DATE
-			GROUP BY p.name
-			ORDER BY qty_terjual DESC
-			LIMIT 1`).Scan(
-		&report.BestSellingProducts.Name,
-		&report.BestSellingProducts.Quantity,
-	)
+// GetTransactionsByDateRange reports on transactions created from (inclusive) to (exclusive)
+func (repo *TransactionRepository) GetTransactionsByDateRange(from time.Time, to time.Time) (*model.TransactionReportRequest, error) {
+	report, err := repo.getReport("t.created_at >= $1 AND t.created_at < $2", from.UTC(), to.UTC())
 	if err != nil {
 		return nil, err
 	}
 
-	return &report, nil
+	if report.TotalTransactions == 0 {
+		return nil, apperror.NotFound("Tidak ada transaksi di periode waktu tersebut")
+	}
+	return report, nil
 }
 
-func (repo *TransactionRepository) GetTransactionsByDateRange(startDate string, endDate string) (*model.TransactionReportRequest, error) {
+// GetReportMetrics returns revenue, transaction count, average basket and items per transaction
+// for transactions created from (inclusive) to (exclusive); an empty period gives zeros
+func (repo *TransactionRepository) GetReportMetrics(from time.Time, to time.Time) (*model.ReportMetrics, error) {
+	var m model.ReportMetrics
+	var items int
+	err := repo.db.QueryRow(`
+		SELECT
+			COUNT(*),
+			COALESCE(SUM(t.total_price), 0),
+			COALESCE(SUM((SELECT SUM(td.quantity) FROM transaction_detail td WHERE td.transaction_id = t.id)), 0)
+		FROM transaction t
+		WHERE t.created_at >= $1 AND t.created_at < $2`, from.UTC(), to.UTC()).Scan(&m.Transactions, &m.Revenue, &items)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Transactions > 0 {
+		m.AvgBasket = math.Round(m.Revenue/float64(m.Transactions)*100) / 100
+		m.ItemsPerTransaction = math.Round(float64(items)/float64(m.Transactions)*100) / 100
+	}
+	return &m, nil
+}
+
+// getReport builds the sales summary, best seller and per-product profit for transactions matching the condition on t
+func (repo *TransactionRepository) getReport(condition string, args ...interface{}) (*model.TransactionReportRequest, error) {
 	var report model.TransactionReportRequest
 
 	err := repo.db.QueryRow(`
 		SELECT
-			COUNT(*) AS total_transaksi, 
+			COUNT(*) AS total_transaksi,
 			COALESCE(SUM(total_price), 0) AS total_revenue
-		FROM transaction
-		WHERE created_at::date BETWEEN $1 AND $2`, startDate, endDate).Scan(
+		FROM transaction t
+		WHERE `+condition, args...).Scan(
 		&report.TotalTransactions,
 		&report.TotalRevenue,
 	)
@@ -135,7 +424,7 @@ func (repo *TransactionRepository) GetTransactionsByDateRange(startDate string,
 	}
 
 	if report.TotalTransactions == 0 {
-		return nil, errors.New("Tidak ada transaksi di periode waktu tersebut")
+		return &report, nil
 	}
 
 	err = repo.db.QueryRow(`
@@ -144,10 +433,10 @@ func (repo *TransactionRepository) GetTransactionsByDateRange(startDate string,
 		FROM transaction_detail td
 		JOIN product p ON td.product_id = p.id
 		JOIN transaction t ON td.transaction_id = t.id
-		WHERE t.created_at::date BETWEEN $1 AND $2
-		GROUP BY p.name
-		ORDER BY qty_terjual DESC
-		LIMIT 1`, startDate, endDate).Scan(
+		WHERE `+condition+`
+		GROUP BY td.product_id, p.name
+		ORDER BY qty_terjual DESC, td.product_id
+		LIMIT 1`, args...).Scan(
 		&report.BestSellingProducts.Name,
 		&report.BestSellingProducts.Quantity,
 	)
@@ -155,5 +444,46 @@ func (repo *TransactionRepository) GetTransactionsByDateRange(startDate string,
 		return nil, err
 	}
 
+	rows, err := repo.db.Query(`
+		SELECT
+			td.product_id, p.name, SUM(td.quantity), SUM(td.subtotal), SUM(td.unit_cost * td.quantity)
+		FROM transaction_detail td
+		JOIN product p ON td.product_id = p.id
+		JOIN transaction t ON td.transaction_id = t.id
+		WHERE `+condition+`
+		GROUP BY td.product_id, p.name
+		ORDER BY SUM(td.subtotal) - SUM(td.unit_cost * td.quantity) DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report.Products = make([]model.ProductProfit, 0)
+	for rows.Next() {
+		var p model.ProductProfit
+		err := rows.Scan(&p.ProductID, &p.Name, &p.Quantity, &p.Revenue, &p.COGS)
+		if err != nil {
+			return nil, err
+		}
+		p.GrossProfit = p.Revenue - p.COGS
+		p.GrossMarginPct = marginPct(p.GrossProfit, p.Revenue)
+		report.TotalCOGS += p.COGS
+		report.Products = append(report.Products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report.GrossProfit = report.TotalRevenue - report.TotalCOGS
+	report.GrossMarginPct = marginPct(report.GrossProfit, report.TotalRevenue)
+
 	return &report, nil
 }
+
+// marginPct returns profit as a percentage of revenue, rounded to two decimals
+func marginPct(profit float64, revenue float64) float64 {
+	if revenue == 0 {
+		return 0
+	}
+	return math.Round(profit/revenue*10000) / 100
+}
@@ -0,0 +1,83 @@
+// Package router mounts handlers on a method and a path pattern with {name} parameters
+// and answers requests that match no route with the JSON error envelope.
+package router
+
+import (
+	"kasir-api/apperror"
+	"kasir-api/middleware"
+	"net/http"
+)
+
+type Middleware func(http.HandlerFunc) http.HandlerFunc
+
+type Router struct {
+	mux        *http.ServeMux
+	middleware []Middleware
+}
+
+// New returns a router that wraps every route in middleware, the first one outermost
+func New(middleware ...Middleware) *Router {
+	return &Router{mux: http.NewServeMux(), middleware: middleware}
+}
+
+// Handle mounts h on method and path, e.g. Handle(http.MethodGet, "/api/v1/products/{id}", h).
+// Each alias serves h on an older path and marks the response as deprecated in favour of path.
+func (rt *Router) Handle(method string, path string, h http.HandlerFunc, aliases ...string) {
+	rt.mux.HandleFunc(method+" "+path, rt.wrap(h))
+	for _, alias := range aliases {
+		rt.mux.HandleFunc(method+" "+alias, rt.wrap(middleware.Deprecated(path)(h)))
+	}
+}
+
+func (rt *Router) wrap(h http.HandlerFunc) http.HandlerFunc {
+	for i := len(rt.middleware) - 1; i >= 0; i-- {
+		h = rt.middleware[i](h)
+	}
+	return h
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, pattern := rt.mux.Handler(r); pattern == "" {
+		// Preflight requests have no route of their own
+		if r.Method == http.MethodOptions {
+			rt.wrap(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			})(w, r)
+			return
+		}
+		// ServeMux answers an unknown path with 404 and a known path with the wrong method
+		// with 405 and an Allow header, both in plain text
+		rt.wrap(func(w http.ResponseWriter, r *http.Request) {
+			rt.mux.ServeHTTP(&errorWriter{ResponseWriter: w, r: r}, r)
+		})(w, r)
+		return
+	}
+	rt.mux.ServeHTTP(w, r)
+}
+
+// errorWriter replaces the plain text body of a 404 or 405 with the JSON error envelope
+type errorWriter struct {
+	http.ResponseWriter
+	r        *http.Request
+	replaced bool
+}
+
+func (e *errorWriter) WriteHeader(status int) {
+	switch status {
+	case http.StatusNotFound:
+		e.replaced = true
+		apperror.WriteStatus(e.ResponseWriter, status, "No route for "+e.r.URL.Path)
+	case http.StatusMethodNotAllowed:
+		e.replaced = true
+		apperror.WriteStatus(e.ResponseWriter, status, "Method "+e.r.Method+" not allowed on "+e.r.URL.Path)
+	default:
+		e.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (e *errorWriter) Write(b []byte) (int, error) {
+	if e.replaced {
+		return len(b), nil
+	}
+	return e.ResponseWriter.Write(b)
+}
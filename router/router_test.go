@@ -0,0 +1,95 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouter(t *testing.T) {
+	tagged := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Middleware", "applied")
+			next(w, r)
+		}
+	}
+	rt := New(tagged)
+	product := func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("product " + r.PathValue("id")))
+	}
+	rt.Handle(http.MethodGet, "/api/v1/products/{id}", product, "/api/produk/{id}")
+	rt.Handle(http.MethodDelete, "/api/v1/products/{id}", product)
+	rt.Handle(http.MethodPost, "/api/v1/products", product)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string   // exact body of a routed request
+		wantCode   string   // error code of the JSON envelope
+		wantAllow  []string // methods listed in the Allow header
+		wantLink   string   // successor of a deprecated alias
+	}{
+		{"route with parameter", http.MethodGet, "/api/v1/products/5", http.StatusOK, "product 5", "", nil, ""},
+		{"deprecated alias", http.MethodGet, "/api/produk/7", http.StatusOK, "product 7", "", nil, `</api/v1/products/7>; rel="successor-version"`},
+		{"unknown path", http.MethodGet, "/api/v1/nothing", http.StatusNotFound, "", "not_found", nil, ""},
+		{"unknown nested path", http.MethodGet, "/api/v1/products/5/nothing", http.StatusNotFound, "", "not_found", nil, ""},
+		{"wrong method", http.MethodPut, "/api/v1/products/5", http.StatusMethodNotAllowed, "", "method_not_allowed", []string{"GET", "DELETE"}, ""},
+		{"wrong method without parameter", http.MethodGet, "/api/v1/products", http.StatusMethodNotAllowed, "", "method_not_allowed", []string{"POST"}, ""},
+		{"preflight", http.MethodOptions, "/api/v1/products/5", http.StatusNoContent, "", "", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			rt.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d (body %s)", w.Code, tt.wantStatus, w.Body)
+			}
+			if got := w.Header().Get("X-Middleware"); got != "applied" {
+				t.Errorf("middleware was not applied")
+			}
+
+			if tt.wantCode != "" {
+				if got := w.Header().Get("Content-Type"); got != "application/json" {
+					t.Errorf("Content-Type = %s, want application/json", got)
+				}
+				var body struct {
+					Error struct {
+						Code    string `json:"code"`
+						Message string `json:"message"`
+					} `json:"error"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatalf("body is not the JSON envelope: %v: %s", err, w.Body)
+				}
+				if body.Error.Code != tt.wantCode || body.Error.Message == "" {
+					t.Errorf("error = %+v, want code %s with a message", body.Error, tt.wantCode)
+				}
+			} else if got := w.Body.String(); got != tt.wantBody {
+				t.Errorf("body = %q, want %q", got, tt.wantBody)
+			}
+
+			allow := w.Header().Get("Allow")
+			for _, method := range tt.wantAllow {
+				if !strings.Contains(allow, method) {
+					t.Errorf("Allow = %q, want it to list %s", allow, method)
+				}
+			}
+			if tt.wantAllow == nil && allow != "" {
+				t.Errorf("Allow = %q, want none", allow)
+			}
+
+			link := w.Header().Get("Link")
+			if link != tt.wantLink {
+				t.Errorf("Link = %q, want %q", link, tt.wantLink)
+			}
+			if deprecated := w.Header().Get("Deprecation") == "true"; deprecated != (tt.wantLink != "") {
+				t.Errorf("Deprecation = %q, want it set only on aliases", w.Header().Get("Deprecation"))
+			}
+		})
+	}
+}
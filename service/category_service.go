@@ -13,11 +13,20 @@ func NewCategoryService(repo *repository.CategoryRepository) *CategoryService {
 	return &CategoryService{repo: repo}
 }
 
-func (s *CategoryService) GetAllCategories() ([]model.Category, error) {
-	return s.repo.GetAllCategories()
+// GetAllCategories returns the page of categories matching filter and the total number of matches
+func (s *CategoryService) GetAllCategories(filter model.CategoryFilter) ([]model.Category, int, error) {
+	var v validator
+	v.listOptions(filter.ListOptions, "id", "name")
+	if err := v.err(); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.GetAllCategories(filter)
 }
 
 func (s *CategoryService) Create(category *model.Category) error {
+	if err := validateCategory(category); err != nil {
+		return err
+	}
 	return s.repo.Create(category)
 }
 
@@ -26,9 +35,19 @@ func (s *CategoryService) GetCategoryByID(id int) (*model.Category, error) {
 }
 
 func (s *CategoryService) Update(category *model.Category) error {
+	if err := validateCategory(category); err != nil {
+		return err
+	}
 	return s.repo.Update(category)
 }
 
 func (s *CategoryService) Delete(id int) error {
 	return s.repo.Delete(id)
 }
+
+func validateCategory(category *model.Category) error {
+	var v validator
+	v.text("category", category.Category, true, 100)
+	v.text("description", category.Description, false, 500)
+	return v.err()
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"kasir-api/apperror"
+	"kasir-api/model"
+	"kasir-api/repository"
+	"time"
+)
+
+type ConsignmentService struct {
+	repo     *repository.ConsignmentRepository
+	location *time.Location
+}
+
+// NewConsignmentService assigns sales to settlement periods by their day in location, the business timezone
+func NewConsignmentService(repo *repository.ConsignmentRepository, location *time.Location) *ConsignmentService {
+	return &ConsignmentService{repo: repo, location: location}
+}
+
+func (s *ConsignmentService) GetConsignmentReport(startDate string, endDate string, supplierID int) (*model.ConsignmentReport, error) {
+	if err := validateDateRange(startDate, endDate); err != nil {
+		return nil, err
+	}
+	from, to := dayBounds(startDate, endDate, s.location)
+	return s.repo.GetConsignmentReport(startDate, endDate, from, to, supplierID)
+}
+
+func (s *ConsignmentService) GetAllSettlements(supplierID int, status string) ([]model.ConsignmentSettlement, error) {
+	return s.repo.GetAllSettlements(supplierID, status)
+}
+
+func (s *ConsignmentService) GetSettlementByID(id int) (*model.ConsignmentSettlement, error) {
+	return s.repo.GetSettlementByID(id)
+}
+
+func (s *ConsignmentService) CreateSettlement(input *model.ConsignmentSettlementInput) (*model.ConsignmentSettlement, error) {
+	if input.SupplierID <= 0 {
+		return nil, apperror.Validation("supplier_id is required")
+	}
+	if err := validateDateRange(input.StartDate, input.EndDate); err != nil {
+		return nil, err
+	}
+	from, to := dayBounds(input.StartDate, input.EndDate, s.location)
+	return s.repo.CreateSettlement(input, from, to)
+}
+
+func (s *ConsignmentService) MarkPaid(id int, input *model.ConsignmentPaymentInput) (*model.ConsignmentSettlement, error) {
+	return s.repo.MarkPaid(id, input)
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"fmt"
+	"kasir-api/model"
+	"kasir-api/repository"
+)
+
+type ModifierService struct {
+	repo *repository.ModifierRepository
+}
+
+func NewModifierService(repo *repository.ModifierRepository) *ModifierService {
+	return &ModifierService{repo: repo}
+}
+
+func (s *ModifierService) GetAllModifierGroups() ([]model.ModifierGroup, error) {
+	return s.repo.GetAllModifierGroups()
+}
+
+func (s *ModifierService) GetModifierGroupsByProductID(productID int) ([]model.ModifierGroup, error) {
+	return s.repo.GetModifierGroupsByProductID(productID)
+}
+
+func (s *ModifierService) GetModifierGroupByID(id int) (*model.ModifierGroup, error) {
+	return s.repo.GetModifierGroupByID(id)
+}
+
+func (s *ModifierService) Create(group *model.ModifierGroup) error {
+	if err := s.validate(group); err != nil {
+		return err
+	}
+	return s.repo.Create(group)
+}
+
+func (s *ModifierService) Update(group *model.ModifierGroup) error {
+	if err := s.validate(group); err != nil {
+		return err
+	}
+	return s.repo.Update(group)
+}
+
+// maxPriceDelta bounds the price change of a modifier option either way
+const maxPriceDelta = 1_000_000_000
+
+// validate checks that the selection limits can be met by the group's options, so a required group can
+// never block checkout of the products it is attached to, that the products exist and that no option
+// takes the price of one of them below zero
+func (s *ModifierService) validate(group *model.ModifierGroup) error {
+	var v validator
+	v.text("name", group.Name, true, 100)
+	v.check(group.MinSelect >= 0, "min_select", "must not be negative")
+	v.check(group.MaxSelect >= 0, "max_select", "must not be negative")
+
+	minSelect := group.MinSelect
+	if group.Required && minSelect < 1 {
+		minSelect = 1
+	}
+	if group.MaxSelect > 0 && minSelect > group.MaxSelect {
+		v.add("max_select", "must be at least %d, the minimum selection", minSelect)
+	}
+	if minSelect > len(group.Options) {
+		v.add("options", "must contain at least %d option(s) to meet the minimum selection", minSelect)
+	}
+
+	productIDs := make([]int, 0, len(group.ProductIDs))
+	for i, id := range group.ProductIDs {
+		if id <= 0 {
+			v.add(fmt.Sprintf("product_ids[%d]", i), "must be a positive ID")
+			continue
+		}
+		productIDs = append(productIDs, id)
+	}
+	prices := make(map[int]float64)
+	if len(productIDs) > 0 {
+		var err error
+		if prices, err = s.repo.ProductPrices(productIDs); err != nil {
+			return err
+		}
+		for i, id := range group.ProductIDs {
+			if _, ok := prices[id]; id > 0 && !ok {
+				v.add(fmt.Sprintf("product_ids[%d]", i), "product %d does not exist", id)
+			}
+		}
+	}
+
+	for i, o := range group.Options {
+		field := fmt.Sprintf("options[%d]", i)
+		v.text(field+".name", o.Name, true, 100)
+		if o.PriceDelta < -maxPriceDelta || o.PriceDelta > maxPriceDelta {
+			v.add(field+".price_delta", "must be between -%d and %d", maxPriceDelta, maxPriceDelta)
+			continue
+		}
+		for _, id := range productIDs {
+			if price, ok := prices[id]; ok && price+o.PriceDelta < 0 {
+				v.add(field+".price_delta", "would take the price of product %d below zero", id)
+				break
+			}
+		}
+	}
+	return v.err()
+}
+
+func (s *ModifierService) Delete(id int) error {
+	return s.repo.Delete(id)
+}
@@ -3,21 +3,84 @@ package service
 import (
 	"kasir-api/model"
 	"kasir-api/repository"
+	"strings"
+	"time"
+	"unicode"
 )
 
 type ProductService struct {
-	repo *repository.ProductRepository
+	repo     *repository.ProductRepository
+	location *time.Location
 }
 
-func NewProductService(repo *repository.ProductRepository) *ProductService {
-	return &ProductService{repo: repo}
+// NewProductService counts recent sales for the search boost by business days in location
+func NewProductService(repo *repository.ProductRepository, location *time.Location) *ProductService {
+	return &ProductService{repo: repo, location: location}
 }
 
-func (s *ProductService) GetAllProducts(name string) ([]model.Product, error) {
-	return s.repo.GetAllProducts(name)
+// searchSalesDays is how many business days of sales, today included, boost a search result
+const searchSalesDays = 30
+
+// GetAllProducts returns the page of products matching filter and the total number of matches
+func (s *ProductService) GetAllProducts(filter model.ProductFilter) ([]model.Product, int, error) {
+	var v validator
+	v.listOptions(filter.ListOptions, "id", "name", "price", "stock")
+	v.check(filter.CategoryID >= 0, "category_id", "must be a positive ID")
+	v.check(filter.MinPrice == nil || *filter.MinPrice >= 0, "min_price", "must not be negative")
+	v.check(filter.MaxPrice == nil || *filter.MaxPrice >= 0, "max_price", "must not be negative")
+	v.check(filter.MinPrice == nil || filter.MaxPrice == nil || *filter.MinPrice <= *filter.MaxPrice,
+		"max_price", "must not be below min_price")
+	if err := v.err(); err != nil {
+		return nil, 0, err
+	}
+	return s.repo.GetAllProducts(filter)
+}
+
+// maxSearchLimit caps the results of a search; search-as-you-type only shows the best few
+const maxSearchLimit = 50
+
+// SearchProducts finds products by name, SKU, barcode or category, tolerating typos, best match first.
+// limit 0 returns 20 results.
+func (s *ProductService) SearchProducts(query string, limit int, boostSales bool) ([]model.ProductSearchResult, error) {
+	query = strings.TrimSpace(query)
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var v validator
+	v.text("q", query, true, 100)
+	v.check(query == "" || len(words) > 0, "q", "must contain a letter or digit")
+	v.check(limit >= 0 && limit <= maxSearchLimit, "limit", "must be between 0 and %d", maxSearchLimit)
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	if limit == 0 {
+		limit = 20
+	}
+
+	// Every word must match the start of a word, so "aqua 600" finds "Aqua 600ml" while the user is still typing
+	terms := make([]string, len(words))
+	for i, word := range words {
+		terms[i] = word + ":*"
+	}
+
+	return s.repo.SearchProducts(model.ProductSearch{
+		Query:      query,
+		TSQuery:    strings.Join(terms, " & "),
+		Limit:      limit,
+		BoostSales: boostSales,
+		SalesSince: time.Now().In(s.location).AddDate(0, 0, 1-searchSalesDays).Format("2006-01-02"),
+	})
+}
+
+func (s *ProductService) GetLowStockProducts() ([]model.Product, error) {
+	return s.repo.GetLowStockProducts()
 }
 
 func (s *ProductService) Create(input *model.ProductInput) (*model.Product, error) {
+	if err := s.validate(input, true); err != nil {
+		return nil, err
+	}
 	return s.repo.Create(input)
 }
 
@@ -26,9 +89,56 @@ func (s *ProductService) GetProductByID(id int) (*model.Product, error) {
 }
 
 func (s *ProductService) Update(id int, input *model.ProductInput) (*model.Product, error) {
+	if err := s.validate(input, false); err != nil {
+		return nil, err
+	}
 	return s.repo.Update(id, input)
 }
 
+// validate checks every field of the input, including that the category and consignor exist.
+// Stock is only set on create; updates must leave it out and go through stock adjustments.
+func (s *ProductService) validate(input *model.ProductInput, create bool) error {
+	var v validator
+	v.text("name", input.Name, true, 100)
+	v.text("sku", input.SKU, false, 50)
+	v.text("barcode", input.Barcode, false, 50)
+	v.check(strings.TrimSpace(input.SKU) == input.SKU, "sku", "must not start or end with spaces")
+	v.check(!strings.ContainsAny(input.Barcode, " \t"), "barcode", "must not contain spaces")
+	v.check(input.Price >= 0, "price", "must not be negative")
+	v.check(input.CostPrice == nil || *input.CostPrice >= 0, "cost_price", "must not be negative")
+	v.check(input.MinStock >= 0, "min_stock", "must not be negative")
+	v.check(input.ReorderQty >= 0, "reorder_qty", "must not be negative")
+	v.check(input.ConsignmentPayout >= 0, "consignment_payout", "must not be negative")
+	v.check(input.ConsignorID != nil || input.ConsignmentPayout == 0, "consignment_payout", "requires consignor_id")
+	if create {
+		v.check(input.Stock >= 0, "stock", "must not be negative")
+		v.check(!input.SerialTracked || input.Stock == 0, "stock",
+			"of a serial-tracked product must be added with its serial numbers through a stock adjustment or goods receipt")
+	} else {
+		v.check(input.Stock == 0, "stock", "cannot be changed on update; use POST /api/v1/products/{id}/stock-adjustments")
+	}
+
+	if input.Category_ID <= 0 {
+		v.add("category_id", "is required")
+	} else {
+		exists, err := s.repo.CategoryExists(input.Category_ID)
+		if err != nil {
+			return err
+		}
+		v.check(exists, "category_id", "category %d does not exist", input.Category_ID)
+	}
+
+	if input.ConsignorID != nil {
+		exists, err := s.repo.SupplierExists(*input.ConsignorID)
+		if err != nil {
+			return err
+		}
+		v.check(exists, "consignor_id", "supplier %d does not exist", *input.ConsignorID)
+	}
+
+	return v.err()
+}
+
 func (s *ProductService) Delete(id int) error {
 	return s.repo.Delete(id)
 }
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"kasir-api/apperror"
+	"kasir-api/model"
+	"kasir-api/repository"
+)
+
+type PurchaseOrderService struct {
+	repo *repository.PurchaseOrderRepository
+}
+
+func NewPurchaseOrderService(repo *repository.PurchaseOrderRepository) *PurchaseOrderService {
+	return &PurchaseOrderService{repo: repo}
+}
+
+func (s *PurchaseOrderService) GetAllPurchaseOrders(status string) ([]model.PurchaseOrder, error) {
+	return s.repo.GetAllPurchaseOrders(status)
+}
+
+func (s *PurchaseOrderService) GetPurchaseOrderByID(id int) (*model.PurchaseOrder, error) {
+	return s.repo.GetPurchaseOrderByID(id)
+}
+
+func (s *PurchaseOrderService) Create(input *model.PurchaseOrderInput) (*model.PurchaseOrder, error) {
+	if len(input.Lines) == 0 {
+		return nil, apperror.Validation("Purchase order must have at least one line")
+	}
+	for _, line := range input.Lines {
+		if line.Quantity <= 0 {
+			return nil, apperror.Validation("Quantity for product %d must be greater than zero", line.ProductID)
+		}
+		if line.ExpectedCost < 0 {
+			return nil, apperror.Validation("Expected cost for product %d must not be negative", line.ProductID)
+		}
+	}
+	return s.repo.Create(input)
+}
+
+func (s *PurchaseOrderService) Cancel(id int) (*model.PurchaseOrder, error) {
+	return s.repo.Cancel(id)
+}
+
+func (s *PurchaseOrderService) Receive(orderID int, input *model.GoodsReceiptInput) (*model.GoodsReceipt, error) {
+	if len(input.Lines) == 0 {
+		return nil, apperror.Validation("Goods receipt must have at least one line")
+	}
+	for _, line := range input.Lines {
+		if line.UnitCost < 0 {
+			return nil, apperror.Validation("Unit cost for line %d must not be negative", line.PurchaseOrderLineID)
+		}
+		if err := validateExpiryDate(line.ExpiryDate); err != nil {
+			return nil, err
+		}
+	}
+	return s.repo.Receive(orderID, input)
+}
@@ -0,0 +1,480 @@
+package service
+
+import (
+	"kasir-api/apperror"
+	"kasir-api/model"
+	"kasir-api/repository"
+	"math"
+	"sort"
+	"time"
+)
+
+type ReportService struct {
+	repo     *repository.ReportRepository
+	location *time.Location
+}
+
+// NewReportService reports days as they fall in location, the business timezone
+func NewReportService(repo *repository.ReportRepository, location *time.Location) *ReportService {
+	return &ReportService{repo: repo, location: location}
+}
+
+// GetReorderSuggestions estimates, from the sales velocity of the last days complete business days (today
+// is left out so the window matches the divisor), how long the current stock lasts and how much to order
+// so it covers coverDays on top of the minimum stock.
+// Suggestions are rounded up to a multiple of the product's reorder quantity.
+func (s *ReportService) GetReorderSuggestions(days int, coverDays int) ([]model.ReorderSuggestion, error) {
+	if days <= 0 || coverDays <= 0 {
+		return nil, apperror.Validation("days and cover_days must be greater than zero")
+	}
+
+	from, to := pastDays(time.Now().In(s.location).AddDate(0, 0, -1), days, s.location)
+	products, err := s.repo.GetSalesVelocity(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]model.ReorderSuggestion, 0)
+	for _, p := range products {
+		p.AvgDailySales = float64(p.QuantitySold) / float64(days)
+		if p.AvgDailySales > 0 {
+			cover := float64(p.Stock) / p.AvgDailySales
+			p.DaysOfCover = &cover
+		}
+
+		target := int(math.Ceil(p.AvgDailySales*float64(coverDays))) + p.MinStock
+		need := target - p.Stock
+		if need <= 0 && p.MinStock > 0 && p.Stock <= p.MinStock {
+			need = 1
+		}
+		if need <= 0 {
+			continue
+		}
+		if p.ReorderQty > 0 {
+			need = int(math.Ceil(float64(need)/float64(p.ReorderQty))) * p.ReorderQty
+		}
+		p.SuggestedQty = need
+		suggestions = append(suggestions, p)
+	}
+
+	// Most urgent first: products without cover data go after those running out
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i].DaysOfCover, suggestions[j].DaysOfCover
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return *a < *b
+	})
+
+	return suggestions, nil
+}
+
+func (s *ReportService) GetExpiringBatches(days int) ([]model.ExpiringBatch, error) {
+	if days < 0 {
+		return nil, apperror.Validation("days must not be negative")
+	}
+	return s.repo.GetExpiringBatches(days, time.Now().In(s.location).Format("2006-01-02"))
+}
+
+func (s *ReportService) GetProductSales(filter model.ProductSalesFilter) ([]model.ProductSales, error) {
+	if err := validateDateRange(filter.StartDate, filter.EndDate); err != nil {
+		return nil, err
+	}
+	if filter.SortBy == "" {
+		filter.SortBy = "quantity"
+	}
+	if filter.SortBy != "quantity" && filter.SortBy != "revenue" && filter.SortBy != "transactions" {
+		return nil, apperror.Validation("sort must be quantity, revenue or transactions")
+	}
+	if filter.Limit < 0 {
+		return nil, apperror.Validation("limit must not be negative")
+	}
+	loc, err := loadLocation(filter.Timezone, s.location)
+	if err != nil {
+		return nil, err
+	}
+	filter.From, filter.To = dayBounds(filter.StartDate, filter.EndDate, loc)
+	filter.Daily = isBusinessLocation(loc, s.location)
+	return s.repo.GetProductSales(filter)
+}
+
+// GetSalesTimeSeries buckets sales by hour, day, week (starting Monday) or month in the local time of tz
+func (s *ReportService) GetSalesTimeSeries(startDate string, endDate string, interval string, tz string) (*model.SalesTimeSeries, error) {
+	if err := validateDateRange(startDate, endDate); err != nil {
+		return nil, err
+	}
+	if interval == "" {
+		interval = "day"
+	}
+	if interval != "hour" && interval != "day" && interval != "week" && interval != "month" {
+		return nil, apperror.Validation("interval must be hour, day, week or month")
+	}
+
+	loc, err := loadLocation(tz, s.location)
+	if err != nil {
+		return nil, err
+	}
+
+	from, to := dayBounds(startDate, endDate, loc)
+	buckets, err := s.repo.GetSalesTimeSeries(startDate, endDate, interval, loc.String(), from, to)
+	if err != nil {
+		return nil, err
+	}
+	for i := range buckets {
+		buckets[i].Start = buckets[i].Start.In(loc)
+	}
+	return &model.SalesTimeSeries{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Interval:  interval,
+		Buckets:   buckets,
+	}, nil
+}
+
+// GetCategorySales compares sales per category with the previous period of the same length,
+// which ends the day before startDate
+func (s *ReportService) GetCategorySales(startDate string, endDate string, tz string) (*model.CategorySalesReport, error) {
+	if err := validateDateRange(startDate, endDate); err != nil {
+		return nil, err
+	}
+	loc, err := loadLocation(tz, s.location)
+	if err != nil {
+		return nil, err
+	}
+	prevStart, prevEnd := comparedPeriod(startDate, endDate, model.ComparePrevious)
+
+	report := &model.CategorySalesReport{
+		StartDate:         startDate,
+		EndDate:           endDate,
+		PreviousStartDate: prevStart,
+		PreviousEndDate:   prevEnd,
+	}
+
+	categorySales := func(startDate string, endDate string) ([]model.CategorySales, error) {
+		if isBusinessLocation(loc, s.location) {
+			return s.repo.GetDailyCategorySales(startDate, endDate)
+		}
+		return s.repo.GetCategorySales(dayBounds(startDate, endDate, loc))
+	}
+
+	current, err := categorySales(report.StartDate, report.EndDate)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := categorySales(report.PreviousStartDate, report.PreviousEndDate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Categories sold only in the previous period are listed after the current ones with zero revenue
+	report.Categories = current
+	index := make(map[int]int)
+	for i, c := range current {
+		index[c.CategoryID] = i
+		report.TotalRevenue += c.Revenue
+	}
+	for _, p := range previous {
+		report.PreviousTotalRevenue += p.Revenue
+		i, ok := index[p.CategoryID]
+		if !ok {
+			i = len(report.Categories)
+			report.Categories = append(report.Categories, model.CategorySales{CategoryID: p.CategoryID, CategoryName: p.CategoryName})
+		}
+		report.Categories[i].PreviousQuantity = p.Quantity
+		report.Categories[i].PreviousRevenue = p.Revenue
+	}
+
+	for i := range report.Categories {
+		c := &report.Categories[i]
+		c.SharePct = percentOf(c.Revenue, report.TotalRevenue)
+		c.RevenueChangePct = percentChange(c.Revenue, c.PreviousRevenue)
+	}
+
+	return report, nil
+}
+
+// percentOf returns part as a percentage of whole, rounded to two decimals
+func percentOf(part float64, whole float64) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return math.Round(part/whole*10000) / 100
+}
+
+// percentChange returns the change from previous to current in percent, or nil when previous is zero
+func percentChange(current float64, previous float64) *float64 {
+	if previous == 0 {
+		return nil
+	}
+	change := math.Round((current-previous)/previous*10000) / 100
+	return &change
+}
+
+// GetSlowMovers lists products in stock that sold at most threshold units in the last days business days,
+// today included. A threshold of zero gives the dead stock.
+func (s *ReportService) GetSlowMovers(days int, threshold int, sortBy string) ([]model.SlowMover, error) {
+	if days <= 0 {
+		return nil, apperror.Validation("days must be greater than zero")
+	}
+	if threshold < 0 {
+		return nil, apperror.Validation("threshold must not be negative")
+	}
+	if sortBy == "" {
+		sortBy = "stock_value"
+	}
+	if sortBy != "stock_value" && sortBy != "days_since_last_sale" && sortBy != "quantity_sold" && sortBy != "stock" {
+		return nil, apperror.Validation("sort must be stock_value, days_since_last_sale, quantity_sold or stock")
+	}
+
+	now := time.Now()
+	from, _ := pastDays(now, days, s.location)
+	products, err := s.repo.GetSlowMovers(from, threshold, sortBy)
+	if err != nil {
+		return nil, err
+	}
+	for i := range products {
+		if last := products[i].LastSoldAt; last != nil {
+			since := int(now.Sub(*last).Hours() / 24)
+			products[i].DaysSinceLastSale = &since
+			*last = last.In(s.location)
+		}
+	}
+	return products, nil
+}
+
+// GetInventoryValuation values the stock on hand now, or at the end of asOf (YYYY-MM-DD) in tz.
+// Past quantities come from the stock movement ledger; prices are the current ones.
+func (s *ReportService) GetInventoryValuation(asOf string, tz string) (*model.InventoryValuation, error) {
+	loc, err := loadLocation(tz, s.location)
+	if err != nil {
+		return nil, err
+	}
+
+	at := time.Now()
+	if asOf != "" {
+		if err := validateDateRange(asOf, asOf); err != nil {
+			return nil, apperror.Validation("as_of must use the YYYY-MM-DD format")
+		}
+		_, end := dayBounds(asOf, asOf, loc)
+		if end.Before(at) {
+			at = end
+		}
+
+		// Past stock is the current stock minus the later ledger movements, so it is only known from the
+		// opening balances on. Earlier stock changes were never recorded and cannot be reconstructed.
+		start, ok, err := s.repo.GetLedgerStart()
+		if err != nil {
+			return nil, err
+		}
+		if ok && at.Before(start) {
+			return nil, apperror.Validation("as_of must be on or after %s, when the stock ledger started",
+				start.In(loc).Format("2006-01-02"))
+		}
+	}
+
+	products, err := s.repo.GetInventoryValuation(at)
+	if err != nil {
+		return nil, err
+	}
+
+	valuation := &model.InventoryValuation{
+		AsOf:       at.In(loc),
+		Categories: make([]model.CategoryInventoryValuation, 0),
+		Products:   products,
+	}
+	index := make(map[int]int)
+	for _, p := range products {
+		i, ok := index[p.CategoryID]
+		if !ok {
+			i = len(valuation.Categories)
+			index[p.CategoryID] = i
+			valuation.Categories = append(valuation.Categories, model.CategoryInventoryValuation{
+				CategoryID:   p.CategoryID,
+				CategoryName: p.CategoryName,
+			})
+		}
+		valuation.Categories[i].Quantity += p.Quantity
+		valuation.Categories[i].Value += p.Value
+		valuation.TotalQuantity += p.Quantity
+		valuation.TotalValue += p.Value
+	}
+	return valuation, nil
+}
+
+// GetProductPairs mines the transactions of the date range in tz for products bought together
+func (s *ReportService) GetProductPairs(startDate string, endDate string, tz string, minCount int, limit int) ([]model.ProductPair, error) {
+	if err := validateDateRange(startDate, endDate); err != nil {
+		return nil, err
+	}
+	if minCount <= 0 || limit <= 0 {
+		return nil, apperror.Validation("min_count and limit must be greater than zero")
+	}
+	loc, err := loadLocation(tz, s.location)
+	if err != nil {
+		return nil, err
+	}
+	from, to := dayBounds(startDate, endDate, loc)
+	return s.repo.GetProductPairs(from, to, minCount, limit)
+}
+
+// GetBoughtWith suggests add-ons for a product from the transactions of the last days business days, today included
+func (s *ReportService) GetBoughtWith(productID int, days int, minCount int, limit int) ([]model.BoughtWith, error) {
+	if days <= 0 {
+		return nil, apperror.Validation("days must be greater than zero")
+	}
+	if minCount <= 0 || limit <= 0 {
+		return nil, apperror.Validation("min_count and limit must be greater than zero")
+	}
+	from, to := pastDays(time.Now(), days, s.location)
+	return s.repo.GetBoughtWith(productID, from, to, minCount, limit)
+}
+
+// GetABCClassification ranks products by their revenue or gross profit in the date range and classifies
+// them by cumulative share: A until thresholdA percent of the total is covered, B until thresholdB, C for
+// the rest. Products without a positive contribution are always C.
+func (s *ReportService) GetABCClassification(startDate string, endDate string, tz string, metric string, thresholdA float64, thresholdB float64) (*model.ABCReport, error) {
+	if err := validateDateRange(startDate, endDate); err != nil {
+		return nil, err
+	}
+	if metric == "" {
+		metric = "revenue"
+	}
+	if metric != "revenue" && metric != "gross_profit" {
+		return nil, apperror.Validation("metric must be revenue or gross_profit")
+	}
+	if thresholdA <= 0 || thresholdA >= thresholdB || thresholdB > 100 {
+		return nil, apperror.Validation("thresholds must satisfy 0 < a < b <= 100")
+	}
+	loc, err := loadLocation(tz, s.location)
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := s.repo.GetProductContribution(dayBounds(startDate, endDate, loc))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.ABCReport{
+		StartDate:  startDate,
+		EndDate:    endDate,
+		Metric:     metric,
+		ThresholdA: thresholdA,
+		ThresholdB: thresholdB,
+	}
+	classifyABC(report, products)
+	return report, nil
+}
+
+// classifyABC ranks products by the report's metric, highest first, and assigns each to class A, B or C
+// by the cumulative share of the total reached before it. Products without a positive value are class C.
+func classifyABC(report *model.ABCReport, products []model.ABCProduct) {
+	value := func(p model.ABCProduct) float64 {
+		if report.Metric == "gross_profit" {
+			return p.GrossProfit
+		}
+		return p.Revenue
+	}
+	sort.SliceStable(products, func(i, j int) bool {
+		return value(products[i]) > value(products[j])
+	})
+
+	report.Classes = []model.ABCClassSummary{
+		{Class: "A"}, {Class: "B"}, {Class: "C"},
+	}
+	report.Products = products
+	for _, p := range products {
+		if v := value(p); v > 0 {
+			report.Total += v
+		}
+	}
+
+	cumulative := 0.0
+	for i := range products {
+		p := &products[i]
+		v := value(*p)
+		p.Rank = i + 1
+
+		// A product belongs to the class whose threshold was not yet reached before it
+		class := 2
+		if v > 0 {
+			before := percentOf(cumulative, report.Total)
+			switch {
+			case before < report.ThresholdA:
+				class = 0
+			case before < report.ThresholdB:
+				class = 1
+			}
+			cumulative += v
+			p.SharePct = percentOf(v, report.Total)
+		}
+		p.CumulativePct = percentOf(cumulative, report.Total)
+		p.Class = report.Classes[class].Class
+
+		report.Classes[class].Products++
+		report.Classes[class].Value += v
+	}
+	for i := range report.Classes {
+		report.Classes[i].SharePct = percentOf(report.Classes[i].Value, report.Total)
+	}
+}
+
+// comparedPeriod returns the dates to compare a validated range with: the period of the same length
+// ending the day before startDate (previous) or the same dates one year earlier (last_year)
+func comparedPeriod(startDate string, endDate string, compare string) (string, string) {
+	start, _ := time.Parse("2006-01-02", startDate)
+	end, _ := time.Parse("2006-01-02", endDate)
+	if compare == model.CompareLastYear {
+		return start.AddDate(-1, 0, 0).Format("2006-01-02"), end.AddDate(-1, 0, 0).Format("2006-01-02")
+	}
+	prevEnd := start.AddDate(0, 0, -1)
+	prevStart := prevEnd.Add(start.Sub(end))
+	return prevStart.Format("2006-01-02"), prevEnd.Format("2006-01-02")
+}
+
+// isBusinessLocation reports whether days in loc are the days of the daily rollups
+func isBusinessLocation(loc *time.Location, business *time.Location) bool {
+	return loc.String() == business.String()
+}
+
+// loadLocation returns the timezone named tz, or def when tz is empty
+func loadLocation(tz string, def *time.Location) (*time.Location, error) {
+	if tz == "" {
+		return def, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, apperror.Validation("Unknown timezone %s", tz)
+	}
+	return loc, nil
+}
+
+// dayBounds returns the UTC instants at which startDate begins and the day after endDate begins in loc.
+// Both dates must already be validated.
+func dayBounds(startDate string, endDate string, loc *time.Location) (time.Time, time.Time) {
+	start, _ := time.ParseInLocation("2006-01-02", startDate, loc)
+	end, _ := time.ParseInLocation("2006-01-02", endDate, loc)
+	return start.UTC(), end.AddDate(0, 0, 1).UTC()
+}
+
+// pastDays returns the bounds of the days business days in loc that end with the day of last
+func pastDays(last time.Time, days int, loc *time.Location) (time.Time, time.Time) {
+	last = last.In(loc)
+	return dayBounds(last.AddDate(0, 0, 1-days).Format("2006-01-02"), last.Format("2006-01-02"), loc)
+}
+
+// validateDateRange checks that both dates use YYYY-MM-DD and that the range is not reversed
+func validateDateRange(startDate string, endDate string) error {
+	start, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		return apperror.Validation("start_date must use the YYYY-MM-DD format")
+	}
+	end, err := time.Parse("2006-01-02", endDate)
+	if err != nil {
+		return apperror.Validation("end_date must use the YYYY-MM-DD format")
+	}
+	if end.Before(start) {
+		return apperror.Validation("end_date must not be before start_date")
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"kasir-api/model"
+	"testing"
+	"time"
+)
+
+func TestComparedPeriod(t *testing.T) {
+	tests := []struct {
+		name      string
+		start     string
+		end       string
+		compare   string
+		wantStart string
+		wantEnd   string
+	}{
+		{"previous month of 31 days", "2026-01-01", "2026-01-31", model.ComparePrevious, "2025-12-01", "2025-12-31"},
+		{"previous single day", "2026-03-01", "2026-03-01", model.ComparePrevious, "2026-02-28", "2026-02-28"},
+		{"previous week across a year end", "2026-01-03", "2026-01-09", model.ComparePrevious, "2025-12-27", "2026-01-02"},
+		{"previous range across a leap day", "2024-03-01", "2024-03-10", model.ComparePrevious, "2024-02-20", "2024-02-29"},
+		{"last year", "2026-01-01", "2026-01-31", model.CompareLastYear, "2025-01-01", "2025-01-31"},
+		{"last year of a single day", "2026-07-15", "2026-07-15", model.CompareLastYear, "2025-07-15", "2025-07-15"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := comparedPeriod(tt.start, tt.end, tt.compare)
+			if start != tt.wantStart || end != tt.wantEnd {
+				t.Errorf("comparedPeriod(%s, %s, %s) = %s, %s; want %s, %s",
+					tt.start, tt.end, tt.compare, start, end, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestDayBounds(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Skip("timezone data not available:", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("timezone data not available:", err)
+	}
+
+	tests := []struct {
+		name     string
+		start    string
+		end      string
+		loc      *time.Location
+		wantFrom string
+		wantTo   string
+	}{
+		{"UTC day", "2026-01-10", "2026-01-10", time.UTC, "2026-01-10T00:00:00Z", "2026-01-11T00:00:00Z"},
+		{"Jakarta month starts the evening before in UTC", "2026-01-01", "2026-01-31", jakarta, "2025-12-31T17:00:00Z", "2026-01-31T17:00:00Z"},
+		{"New York day of the switch to daylight saving time is 23 hours", "2026-03-08", "2026-03-08", newYork, "2026-03-08T05:00:00Z", "2026-03-09T04:00:00Z"},
+		{"New York day of the switch back is 25 hours", "2026-11-01", "2026-11-01", newYork, "2026-11-01T04:00:00Z", "2026-11-02T05:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := dayBounds(tt.start, tt.end, tt.loc)
+			if got := from.Format(time.RFC3339); got != tt.wantFrom {
+				t.Errorf("from = %s, want %s", got, tt.wantFrom)
+			}
+			if got := to.Format(time.RFC3339); got != tt.wantTo {
+				t.Errorf("to = %s, want %s", got, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestClassifyABC(t *testing.T) {
+	tests := []struct {
+		name        string
+		metric      string
+		values      []float64 // revenue, or gross profit for the gross_profit metric, by product ID 1, 2, ...
+		wantIDs     []int     // product IDs in rank order
+		wantClasses string    // classes in rank order
+		wantCounts  [3]int    // products in A, B and C
+	}{
+		{"product reaching the A threshold exactly starts B", "revenue", []float64{50, 30, 15, 5}, []int{1, 2, 3, 4}, "AABC", [3]int{2, 1, 1}},
+		{"product starting below the A threshold is A", "revenue", []float64{60, 19, 16, 5}, []int{1, 2, 3, 4}, "AAAC", [3]int{3, 0, 1}},
+		{"product starting below the B threshold is B", "revenue", []float64{80, 14, 6}, []int{1, 2, 3}, "ABB", [3]int{1, 2, 0}},
+		{"sorted by value, ties keep their order", "revenue", []float64{10, 40, 10, 40}, []int{2, 4, 1, 3}, "AABB", [3]int{2, 2, 0}},
+		{"products without a positive value are C", "gross_profit", []float64{-20, 70, 0, 30}, []int{2, 4, 3, 1}, "AACC", [3]int{2, 0, 2}},
+		{"single product is A", "revenue", []float64{1000}, []int{1}, "A", [3]int{1, 0, 0}},
+		{"no sales at all", "revenue", []float64{0, 0}, []int{1, 2}, "CC", [3]int{0, 0, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			products := make([]model.ABCProduct, len(tt.values))
+			for i, v := range tt.values {
+				products[i] = model.ABCProduct{ProductID: i + 1, Revenue: v}
+				if tt.metric == "gross_profit" {
+					products[i] = model.ABCProduct{ProductID: i + 1, Revenue: 100, GrossProfit: v}
+				}
+			}
+			report := &model.ABCReport{Metric: tt.metric, ThresholdA: 80, ThresholdB: 95}
+			classifyABC(report, products)
+
+			var classes string
+			for i, p := range report.Products {
+				classes += p.Class
+				if p.ProductID != tt.wantIDs[i] {
+					t.Errorf("rank %d is product %d, want %d", i+1, p.ProductID, tt.wantIDs[i])
+				}
+				if p.Rank != i+1 {
+					t.Errorf("product %d has rank %d, want %d", p.ProductID, p.Rank, i+1)
+				}
+			}
+			if classes != tt.wantClasses {
+				t.Errorf("classes = %s, want %s", classes, tt.wantClasses)
+			}
+			for i, c := range report.Classes {
+				if c.Products != tt.wantCounts[i] {
+					t.Errorf("class %s has %d products, want %d", c.Class, c.Products, tt.wantCounts[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPastDays(t *testing.T) {
+	jakarta, err := time.LoadLocation("Asia/Jakarta")
+	if err != nil {
+		t.Skip("timezone data not available:", err)
+	}
+
+	tests := []struct {
+		name     string
+		last     time.Time
+		days     int
+		wantFrom string
+		wantTo   string
+	}{
+		{"single day", time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC), 1, "2026-01-09T17:00:00Z", "2026-01-10T17:00:00Z"},
+		{"week", time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC), 7, "2026-01-03T17:00:00Z", "2026-01-10T17:00:00Z"},
+		{"UTC evening is already the next business day", time.Date(2026, 1, 10, 20, 0, 0, 0, time.UTC), 1, "2026-01-10T17:00:00Z", "2026-01-11T17:00:00Z"},
+		{"across a month end", time.Date(2026, 3, 2, 3, 0, 0, 0, time.UTC), 3, "2026-02-27T17:00:00Z", "2026-03-02T17:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := pastDays(tt.last, tt.days, jakarta)
+			if got := from.Format(time.RFC3339); got != tt.wantFrom {
+				t.Errorf("from = %s, want %s", got, tt.wantFrom)
+			}
+			if got := to.Format(time.RFC3339); got != tt.wantTo {
+				t.Errorf("to = %s, want %s", got, tt.wantTo)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"kasir-api/model"
+	"kasir-api/repository"
+)
+
+type SerialService struct {
+	repo            *repository.SerialRepository
+	transactionRepo *repository.TransactionRepository
+}
+
+func NewSerialService(repo *repository.SerialRepository, transactionRepo *repository.TransactionRepository) *SerialService {
+	return &SerialService{repo: repo, transactionRepo: transactionRepo}
+}
+
+// LookupSerial returns every product the serial is registered for, with the transaction it was sold in
+func (s *SerialService) LookupSerial(serialNumber string) ([]model.SerialLookup, error) {
+	results, err := s.repo.LookupSerial(serialNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		if results[i].TransactionID == 0 {
+			continue
+		}
+		results[i].Transaction, err = s.transactionRepo.GetTransactionByID(results[i].TransactionID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"kasir-api/apperror"
+	"kasir-api/model"
+	"kasir-api/repository"
+	"unicode/utf8"
+)
+
+type StockCountService struct {
+	repo *repository.StockCountRepository
+}
+
+func NewStockCountService(repo *repository.StockCountRepository) *StockCountService {
+	return &StockCountService{repo: repo}
+}
+
+func (s *StockCountService) GetAllStockCounts() ([]model.StockCount, error) {
+	return s.repo.GetAllStockCounts()
+}
+
+func (s *StockCountService) GetStockCountByID(id int) (*model.StockCount, error) {
+	return s.repo.GetStockCountByID(id)
+}
+
+func (s *StockCountService) Create(input *model.StockCountInput) (*model.StockCount, error) {
+	return s.repo.Create(input)
+}
+
+func (s *StockCountService) AddEntries(id int, input *model.StockCountEntryInput) (*model.StockCount, error) {
+	if len(input.Items) == 0 {
+		return nil, apperror.Validation("Items must not be empty")
+	}
+	if utf8.RuneCountInString(input.BatchKey) > 100 {
+		return nil, apperror.Validation("Batch key must be at most 100 characters")
+	}
+	for _, item := range input.Items {
+		if item.Quantity < 0 {
+			return nil, apperror.Validation("Quantity for product %d must not be negative", item.ProductID)
+		}
+	}
+	return s.repo.AddEntries(id, input)
+}
+
+func (s *StockCountService) GetVarianceReport(id int) (*model.StockCountVarianceReport, error) {
+	return s.repo.GetVarianceReport(id)
+}
+
+func (s *StockCountService) Approve(id int, input *model.StockCountApprovalInput) (*model.StockCount, error) {
+	return s.repo.Approve(id, input)
+}
+
+func (s *StockCountService) Cancel(id int) (*model.StockCount, error) {
+	return s.repo.Cancel(id)
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"fmt"
+	"kasir-api/apperror"
+	"kasir-api/model"
+	"kasir-api/repository"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type StockService struct {
+	repo      *repository.StockRepository
+	batchRepo *repository.BatchRepository
+}
+
+func NewStockService(repo *repository.StockRepository, batchRepo *repository.BatchRepository) *StockService {
+	return &StockService{repo: repo, batchRepo: batchRepo}
+}
+
+func (s *StockService) GetStockHistory(productID int) ([]model.StockMovement, error) {
+	return s.repo.GetStockHistory(productID)
+}
+
+func (s *StockService) Adjust(productID int, input *model.StockAdjustmentInput) (*model.StockMovement, error) {
+	if input.Type == "" {
+		input.Type = model.StockMovementAdjustment
+	}
+	switch input.Type {
+	case model.StockMovementAdjustment, model.StockMovementTransfer, model.StockMovementRefund:
+	default:
+		return nil, apperror.Validation("Type must be adjustment, transfer or refund")
+	}
+	if input.QuantityChange == 0 {
+		return nil, apperror.Validation("Quantity change must not be zero")
+	}
+	if input.Type == model.StockMovementAdjustment && input.Reason == "" {
+		return nil, apperror.Validation("Reason is required for stock adjustments")
+	}
+	if input.Type == model.StockMovementRefund {
+		if input.QuantityChange < 0 {
+			return nil, apperror.Validation("Quantity change of a refund must be positive")
+		}
+		id, ok := strings.CutPrefix(input.Reference, "TRX-")
+		transactionID, err := strconv.Atoi(id)
+		if !ok || err != nil || transactionID <= 0 {
+			return nil, apperror.Validation("Reference of a refund must name the refunded transaction as TRX-{id}")
+		}
+		// Earlier refunds are found by reference, so it is stored in one spelling
+		input.TransactionID = transactionID
+		input.Reference = fmt.Sprintf("TRX-%d", transactionID)
+	}
+	if err := validateExpiryDate(input.ExpiryDate); err != nil {
+		return nil, err
+	}
+	return s.repo.Adjust(productID, input)
+}
+
+func (s *StockService) GetBatches(productID int) ([]model.ProductBatch, error) {
+	return s.batchRepo.GetBatchesByProductID(productID)
+}
+
+func validateExpiryDate(date string) error {
+	if date == "" {
+		return nil
+	}
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		return apperror.Validation("Expiry date must use the YYYY-MM-DD format")
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"kasir-api/model"
+	"kasir-api/repository"
+)
+
+type SupplierService struct {
+	repo *repository.SupplierRepository
+}
+
+func NewSupplierService(repo *repository.SupplierRepository) *SupplierService {
+	return &SupplierService{repo: repo}
+}
+
+func (s *SupplierService) GetAllSuppliers() ([]model.Supplier, error) {
+	return s.repo.GetAllSuppliers()
+}
+
+func (s *SupplierService) Create(supplier *model.Supplier) error {
+	return s.repo.Create(supplier)
+}
+
+func (s *SupplierService) GetSupplierByID(id int) (*model.Supplier, error) {
+	return s.repo.GetSupplierByID(id)
+}
+
+func (s *SupplierService) Update(supplier *model.Supplier) error {
+	return s.repo.Update(supplier)
+}
+
+func (s *SupplierService) Delete(id int) error {
+	return s.repo.Delete(id)
+}
+
+func (s *SupplierService) GetPurchaseHistory(supplierID int) ([]model.SupplierPurchase, error) {
+	return s.repo.GetPurchaseHistory(supplierID)
+}
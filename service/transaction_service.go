@@ -1,26 +1,222 @@
 package service
 
 import (
+	"fmt"
+	"kasir-api/apperror"
 	"kasir-api/model"
 	"kasir-api/repository"
+	"math"
+	"strings"
+	"time"
 )
 
 type TransactionService struct {
-	repo *repository.TransactionRepository
+	repo     *repository.TransactionRepository
+	location *time.Location
 }
 
-func NewTransactionService(repo *repository.TransactionRepository) *TransactionService {
-	return &TransactionService{repo: repo}
+// NewTransactionService reports days as they fall in location, the business timezone
+func NewTransactionService(repo *repository.TransactionRepository, location *time.Location) *TransactionService {
+	return &TransactionService{repo: repo, location: location}
 }
 
-func (s *TransactionService) Checkout(items []model.CheckoutItem) (*model.Transaction, error) {
-	return s.repo.Checkout(items)
+func (s *TransactionService) Checkout(request *model.CheckoutRequest) (*model.Transaction, error) {
+	if err := s.validateCheckout(request); err != nil {
+		return nil, err
+	}
+	return s.repo.Checkout(request, s.location)
 }
 
-func (s *TransactionService) GetTodayTransactions() (*model.TransactionReportRequest, error) {
-	return s.repo.GetTodayTransactions()
+// validateCheckout checks the customer fields and every item, including that the products exist.
+// Stock, modifier rules and serial numbers are checked by the checkout itself.
+func (s *TransactionService) validateCheckout(request *model.CheckoutRequest) error {
+	var v validator
+	v.text("customer_name", request.CustomerName, false, 100)
+	v.text("customer_phone", request.CustomerPhone, false, 20)
+	v.check(strings.Trim(request.CustomerPhone, "+0123456789 -") == "", "customer_phone",
+		"may only contain digits, spaces, dashes and +")
+	v.check(len(request.Items) > 0, "items", "must contain at least one item")
+
+	productIDs := make([]int, 0, len(request.Items))
+	for i, item := range request.Items {
+		field := fmt.Sprintf("items[%d]", i)
+		v.check(item.ProductID > 0, field+".product_id", "is required")
+		v.check(item.Quantity > 0, field+".quantity", "must be greater than zero")
+		v.text(field+".note", item.Note, false, 255)
+		for j, id := range item.ModifierIDs {
+			v.check(id > 0, fmt.Sprintf("%s.modifier_ids[%d]", field, j), "must be a positive ID")
+		}
+		if item.ProductID > 0 {
+			productIDs = append(productIDs, item.ProductID)
+		}
+	}
+
+	if len(productIDs) > 0 {
+		existing, err := s.repo.ExistingProductIDs(productIDs)
+		if err != nil {
+			return err
+		}
+		for i, item := range request.Items {
+			if item.ProductID > 0 && !existing[item.ProductID] {
+				v.add(fmt.Sprintf("items[%d].product_id", i), "product %d does not exist", item.ProductID)
+			}
+		}
+	}
+
+	return v.err()
+}
+
+// GetTodayTransactions reports on the current day in tz, or in the business timezone when tz is empty.
+// compare adds the figures of yesterday (previous) or the same day last year (last_year).
+func (s *TransactionService) GetTodayTransactions(tz string, compare string) (*model.TransactionReportRequest, error) {
+	if err := validateCompareMode(compare); err != nil {
+		return nil, err
+	}
+	loc, err := loadLocation(tz, s.location)
+	if err != nil {
+		return nil, err
+	}
+	today := time.Now().In(loc).Format("2006-01-02")
+	from, to := dayBounds(today, today, loc)
+	report, err := s.repo.GetTodayTransactions(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return s.withComparison(report, today, today, loc, compare)
+}
+
+// GetTransactionsByDateRange reports on the date range in tz. compare adds the figures of the previous
+// period of the same length (previous) or of the same dates last year (last_year).
+func (s *TransactionService) GetTransactionsByDateRange(startDate string, endDate string, tz string, compare string) (*model.TransactionReportRequest, error) {
+	if err := validateCompareMode(compare); err != nil {
+		return nil, err
+	}
+	from, to, loc, err := s.dateRange(startDate, endDate, tz)
+	if err != nil {
+		return nil, err
+	}
+
+	var report *model.TransactionReportRequest
+	if isBusinessLocation(loc, s.location) {
+		report, err = s.repo.GetDailySalesReport(startDate, endDate)
+	} else {
+		report, err = s.repo.GetTransactionsByDateRange(from, to)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.withComparison(report, startDate, endDate, loc, compare)
+}
+
+// withComparison attaches the metrics of the current and compared period with their changes
+func (s *TransactionService) withComparison(report *model.TransactionReportRequest, startDate string, endDate string, loc *time.Location, compare string) (*model.TransactionReportRequest, error) {
+	if compare == model.CompareNone {
+		return report, nil
+	}
+
+	metrics := func(startDate string, endDate string) (*model.ReportMetrics, error) {
+		if isBusinessLocation(loc, s.location) {
+			return s.repo.GetDailyReportMetrics(startDate, endDate)
+		}
+		return s.repo.GetReportMetrics(dayBounds(startDate, endDate, loc))
+	}
+
+	prevStart, prevEnd := comparedPeriod(startDate, endDate, compare)
+	current, err := metrics(startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := metrics(prevStart, prevEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &model.ReportComparison{
+		Mode:              compare,
+		StartDate:         startDate,
+		EndDate:           endDate,
+		PreviousStartDate: prevStart,
+		PreviousEndDate:   prevEnd,
+		Current:           *current,
+		Previous:          *previous,
+	}
+	c.Change.Revenue = metricChange(current.Revenue, previous.Revenue)
+	c.Change.Transactions = metricChange(float64(current.Transactions), float64(previous.Transactions))
+	c.Change.AvgBasket = metricChange(current.AvgBasket, previous.AvgBasket)
+	c.Change.ItemsPerTransaction = metricChange(current.ItemsPerTransaction, previous.ItemsPerTransaction)
+	report.Comparison = c
+	return report, nil
+}
+
+func validateCompareMode(compare string) error {
+	if compare != model.CompareNone && compare != model.ComparePrevious && compare != model.CompareLastYear {
+		return apperror.Validation("compare must be previous or last_year")
+	}
+	return nil
+}
+
+func metricChange(current float64, previous float64) model.MetricChange {
+	return model.MetricChange{
+		Absolute: math.Round((current-previous)*100) / 100,
+		Pct:      percentChange(current, previous),
+	}
+}
+
+// EachTransaction streams the transactions of the date range in tz to fn, with created_at in that timezone
+func (s *TransactionService) EachTransaction(startDate string, endDate string, tz string, fn func(model.TransactionSummary) error) error {
+	from, to, loc, err := s.dateRange(startDate, endDate, tz)
+	if err != nil {
+		return err
+	}
+	return s.repo.EachTransaction(from, to, func(t model.TransactionSummary) error {
+		t.CreatedAt = t.CreatedAt.In(loc)
+		return fn(t)
+	})
+}
+
+// EachTransactionLine streams the sold lines of the date range in tz to fn, with created_at in that timezone
+func (s *TransactionService) EachTransactionLine(startDate string, endDate string, tz string, fn func(model.TransactionLine) error) error {
+	from, to, loc, err := s.dateRange(startDate, endDate, tz)
+	if err != nil {
+		return err
+	}
+	return s.repo.EachTransactionLine(from, to, func(l model.TransactionLine) error {
+		l.CreatedAt = l.CreatedAt.In(loc)
+		return fn(l)
+	})
+}
+
+// dateRange validates the dates and returns their UTC bounds in tz
+func (s *TransactionService) dateRange(startDate string, endDate string, tz string) (time.Time, time.Time, *time.Location, error) {
+	if err := validateDateRange(startDate, endDate); err != nil {
+		return time.Time{}, time.Time{}, nil, err
+	}
+	loc, err := loadLocation(tz, s.location)
+	if err != nil {
+		return time.Time{}, time.Time{}, nil, err
+	}
+	from, to := dayBounds(startDate, endDate, loc)
+	return from, to, loc, nil
+}
+
+// RebuildDailySales recomputes the daily rollups of startDate to endDate (business days) from the
+// transactions, or of every day with sales when both are empty. It returns the number of days with sales.
+func (s *TransactionService) RebuildDailySales(startDate string, endDate string) (int, error) {
+	tz := s.location.String()
+	if startDate == "" && endDate == "" {
+		first, last, err := s.repo.GetSalesDateRange(tz)
+		if err != nil || first == "" {
+			return 0, err
+		}
+		startDate, endDate = first, last
+	}
+	if err := validateDateRange(startDate, endDate); err != nil {
+		return 0, err
+	}
+	from, to := dayBounds(startDate, endDate, s.location)
+	return s.repo.RebuildDailySales(startDate, endDate, tz, from, to)
 }
 
-func (s *TransactionService) GetTransactionsByDateRange(startDate string, endDate string) (*model.TransactionReportRequest, error) {
-	return s.repo.GetTransactionsByDateRange(startDate, endDate)
+func (s *TransactionService) GetTransactionByID(id int) (*model.Transaction, error) {
+	return s.repo.GetTransactionByID(id)
 }
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"fmt"
+	"kasir-api/apperror"
+	"kasir-api/model"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// validator collects every invalid field of a request so they are reported at once
+type validator struct {
+	fields []apperror.FieldError
+}
+
+func (v *validator) add(field string, format string, args ...interface{}) {
+	v.fields = append(v.fields, apperror.FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// check adds the message when ok is false
+func (v *validator) check(ok bool, field string, format string, args ...interface{}) {
+	if !ok {
+		v.add(field, format, args...)
+	}
+}
+
+// text checks that a string is within max characters and, when required, not blank
+func (v *validator) text(field string, value string, required bool, max int) {
+	switch {
+	case required && strings.TrimSpace(value) == "":
+		v.add(field, "is required")
+	case utf8.RuneCountInString(value) > max:
+		v.add(field, "must be at most %d characters", max)
+	}
+}
+
+// err returns the collected field errors as one validation error, or nil when there are none
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return apperror.ValidationFields(v.fields)
+}
+
+// maxListLimit caps the page size of list endpoints; a zero limit gets the repository default of 50
+const maxListLimit = 500
+
+// listOptions checks the paging of a list and that it sorts by one of sortFields
+func (v *validator) listOptions(opts model.ListOptions, sortFields ...string) {
+	if opts.SortBy != "" && !slices.Contains(sortFields, opts.SortBy) {
+		v.add("sort", "must be one of %s", strings.Join(sortFields, ", "))
+	}
+	v.check(opts.Order == "" || opts.Order == "asc" || opts.Order == "desc", "order", "must be asc or desc")
+	v.check(opts.Limit >= 0 && opts.Limit <= maxListLimit, "limit", "must be between 0 and %d", maxListLimit)
+	v.check(opts.Offset >= 0, "offset", "must not be negative")
+}